@@ -0,0 +1,130 @@
+package aks
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// acrPullRoles are the roles that allow pulling images from a container registry
+var acrPullRoles = []string{"AcrPull", "AcrPush", "Contributor", "Owner"}
+
+// AttachACR grants the current cluster's kubelet identity pull access to the container registry
+func (s *Service) AttachACR(ctx context.Context, registry string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	cmd := exec.Command(
+		"az", "aks", "update",
+		"--resource-group", cfg.ResourceGroup,
+		"--name", cfg.ClusterName,
+		"--subscription", s.subscriptionID,
+		"--attach-acr", registry,
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+	spinnerDone := runSpinner("attaching container registry...")
+
+	output, err := retry.CombinedOutput(cmd)
+
+	close(spinnerDone)
+
+	if err != nil {
+		return fmt.Errorf("failed to attach container registry '%s': %w", registry, clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// CanPullFromACR checks if the current cluster's kubelet identity has a role on the container
+// registry that allows pulling images
+func (s *Service) CanPullFromACR(ctx context.Context, registry string) (bool, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return false, fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	kubeletObjectID, err := s.GetKubeletIdentityObjectID(cfg.ClusterName, cfg.ResourceGroup)
+	if err != nil {
+		return false, err
+	}
+
+	cmd := exec.Command(
+		"az", "acr", "show",
+		"--name", registry,
+		"--subscription", s.subscriptionID,
+		"--query", "id",
+		"--output", "tsv",
+	)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return false, fmt.Errorf("failed to find container registry '%s': %w", registry, clierror.New(err, stderr))
+	}
+	registryID := strings.TrimSpace(string(output))
+
+	cmd = exec.Command(
+		"az", "role", "assignment", "list",
+		"--assignee", kubeletObjectID,
+		"--scope", registryID,
+		"--include-inherited",
+		"--subscription", s.subscriptionID,
+		"--query", "[].roleDefinitionName",
+		"--output", "tsv",
+	)
+
+	output, stderr, err = retry.Output(cmd)
+	if err != nil {
+		return false, fmt.Errorf("failed to list role assignments of the kubelet identity: %w", clierror.New(err, stderr))
+	}
+
+	for _, role := range strings.Split(string(output), "\n") {
+		if slices.Contains(acrPullRoles, strings.TrimSpace(role)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetKubeletIdentityObjectID returns the object ID of the identity the cluster's nodes pull images with
+func (s *Service) GetKubeletIdentityObjectID(clusterName, resourceGroup string) (string, error) {
+	cmd := exec.Command(
+		"az", "aks", "show",
+		"--name", clusterName,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "identityProfile.kubeletidentity.objectId",
+		"--output", "tsv",
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubelet identity of cluster '%s': %w", clusterName, clierror.New(err, stderr))
+	}
+
+	objectID := strings.TrimSpace(string(output))
+	if objectID == "" {
+		return "", fmt.Errorf("cluster '%s' has no kubelet managed identity", clusterName)
+	}
+
+	return objectID, nil
+}
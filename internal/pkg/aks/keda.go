@@ -0,0 +1,203 @@
+package aks
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/kube"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+const (
+	// kedaOperatorSubject is the service account the AKS KEDA add-on runs its operator as
+	kedaOperatorSubject = "system:serviceaccount:kube-system:keda-operator"
+
+	// KEDAServiceBusRole is the role the KEDA azure-servicebus scaler needs. It reads the message count
+	// from the queue's entity description, which requires the Manage claim of Data Owner.
+	KEDAServiceBusRole = "Azure Service Bus Data Owner"
+)
+
+// CheckKEDA checks if the KEDA add-on is enabled on the current cluster
+func (s *Service) CheckKEDA(ctx context.Context) (bool, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return false, fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	cmd := exec.Command(
+		"az", "aks", "show",
+		"--resource-group", cfg.ResourceGroup,
+		"--name", cfg.ClusterName,
+		"--subscription", s.subscriptionID,
+		"--query", "workloadAutoScalerProfile.keda.enabled",
+		"--output", "tsv",
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return false, fmt.Errorf("failed to check KEDA: %w", clierror.New(err, stderr))
+	}
+
+	result := strings.TrimSpace(string(output))
+	return result == "true", nil
+}
+
+// EnableKEDA enables the KEDA add-on on the current cluster
+func (s *Service) EnableKEDA(ctx context.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	cmd := exec.Command(
+		"az", "aks", "update",
+		"--resource-group", cfg.ResourceGroup,
+		"--name", cfg.ClusterName,
+		"--subscription", s.subscriptionID,
+		"--enable-keda",
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+	spinnerDone := runSpinner("enabling KEDA...")
+
+	output, err := retry.CombinedOutput(cmd)
+
+	close(spinnerDone)
+
+	if err != nil {
+		return fmt.Errorf("failed to enable KEDA: %w", clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// FederateKEDAOperator lets the KEDA operator authenticate as the managed identity, so that
+// scalers using workload identity can query Azure services
+func (s *Service) FederateKEDAOperator(ctx context.Context, identityName string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	credName := fmt.Sprintf("%s-keda-operator", identityName)
+	checkCmd := exec.Command(
+		"az", "identity", "federated-credential", "list",
+		"--identity-name", identityName,
+		"--resource-group", cfg.ResourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", fmt.Sprintf("[?name=='%s'].name", credName),
+		"--output", "tsv",
+	)
+
+	output, stderr, err := retry.Output(checkCmd)
+	if err != nil {
+		return fmt.Errorf("failed to list federated identity credentials: %w", clierror.New(err, stderr))
+	}
+
+	if strings.TrimSpace(string(output)) == credName {
+		return nil
+	}
+
+	oidcURL, err := s.getClusterOIDCIssuerURL(cfg.ClusterName, cfg.ResourceGroup)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster OIDC issuer URL: %w", err)
+	}
+
+	if err := s.createFederatedCredentialForSubject(credName, identityName, cfg.ResourceGroup, oidcURL, kedaOperatorSubject); err != nil {
+		return err
+	}
+
+	if err := kube.GetCredentials(s.subscriptionID, cfg.ClusterName, cfg.ResourceGroup); err != nil {
+		return err
+	}
+
+	// The KEDA operator only picks up the new federation after a restart
+	fmt.Println("Restarting KEDA operator...")
+	restartCmd := exec.Command("kubectl", "rollout", "restart", "deployment", "keda-operator", "-n", "kube-system")
+	output, err = retry.CombinedOutput(restartCmd)
+	if err != nil {
+		return fmt.Errorf("failed to restart KEDA operator: %w", clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// CheckKEDAServiceBusRole checks whether the managed identity holds KEDAServiceBusRole on the Service Bus
+// queue, directly or through its namespace, resource group or subscription
+func (s *Service) CheckKEDAServiceBusRole(ctx context.Context, identityName, namespace, queue string) (bool, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	cmd := exec.Command(
+		"az", "servicebus", "namespace", "list",
+		"--subscription", s.subscriptionID,
+		"--query", fmt.Sprintf("[?name=='%s'].id", namespace),
+		"--output", "tsv",
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return false, fmt.Errorf("failed to find Service Bus namespace '%s': %w", namespace, clierror.New(err, stderr))
+	}
+
+	namespaceID := strings.TrimSpace(string(output))
+	if namespaceID == "" {
+		return false, fmt.Errorf("Service Bus namespace '%s' not found in the subscription", namespace)
+	}
+
+	cmd = exec.Command(
+		"az", "identity", "show",
+		"--name", identityName,
+		"--resource-group", cfg.ResourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "principalId",
+		"--output", "tsv",
+	)
+
+	output, stderr, err = retry.Output(cmd)
+	if err != nil {
+		return false, fmt.Errorf("failed to get identity principal ID: %w", clierror.New(err, stderr))
+	}
+
+	cmd = exec.Command(
+		"az", "role", "assignment", "list",
+		"--assignee", strings.TrimSpace(string(output)),
+		"--role", KEDAServiceBusRole,
+		"--scope", fmt.Sprintf("%s/queues/%s", namespaceID, queue),
+		"--include-inherited",
+		"--subscription", s.subscriptionID,
+		"--query", "[].id",
+		"--output", "tsv",
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, stderr, err = retry.Output(cmd)
+	if err != nil {
+		return false, fmt.Errorf("failed to list role assignments: %w", clierror.New(err, stderr))
+	}
+
+	return strings.TrimSpace(string(output)) != "", nil
+}
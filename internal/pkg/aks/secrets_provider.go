@@ -0,0 +1,78 @@
+package aks
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// CheckKeyVaultSecretsProvider checks if the Azure Key Vault provider for the Secrets Store CSI driver
+// is enabled on the current cluster
+func (s *Service) CheckKeyVaultSecretsProvider(ctx context.Context) (bool, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return false, fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	cmd := exec.Command(
+		"az", "aks", "show",
+		"--resource-group", cfg.ResourceGroup,
+		"--name", cfg.ClusterName,
+		"--subscription", s.subscriptionID,
+		"--query", "addonProfiles.azureKeyvaultSecretsProvider.enabled",
+		"--output", "tsv",
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return false, fmt.Errorf("failed to check Key Vault secrets provider: %w", clierror.New(err, stderr))
+	}
+
+	result := strings.TrimSpace(string(output))
+	return result == "true", nil
+}
+
+// EnableKeyVaultSecretsProvider enables the Azure Key Vault provider for the Secrets Store CSI driver
+// on the current cluster
+func (s *Service) EnableKeyVaultSecretsProvider(ctx context.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	cmd := exec.Command(
+		"az", "aks", "enable-addons",
+		"--addons", "azure-keyvault-secrets-provider",
+		"--resource-group", cfg.ResourceGroup,
+		"--name", cfg.ClusterName,
+		"--subscription", s.subscriptionID,
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+	spinnerDone := runSpinner("enabling Key Vault secrets provider...")
+
+	output, err := retry.CombinedOutput(cmd)
+
+	close(spinnerDone)
+
+	if err != nil {
+		return fmt.Errorf("failed to enable Key Vault secrets provider: %w", clierror.New(err, output))
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package aks
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,7 +10,9 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
 	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
 )
 
 func runSpinner(prefix string) chan struct{} {
@@ -74,12 +77,12 @@ func (s *Service) CreateCluster(ctx context.Context, resourceGroup, clusterName,
 	spinnerDone := runSpinner("creating AKS cluster...")
 
 	cmd := exec.Command("az", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := retry.CombinedOutput(cmd)
 
 	close(spinnerDone)
 
 	if err != nil {
-		return fmt.Errorf("failed to create AKS cluster: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to create AKS cluster: %w", clierror.New(err, output))
 	}
 
 	cfg, err := config.LoadConfig()
@@ -108,9 +111,13 @@ func (s *Service) GetCluster(ctx context.Context, resourceGroup, clusterName str
 
 	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
 
-	output, err := cmd.CombinedOutput()
+	output, err := retry.CombinedOutput(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to get AKS cluster: %w\nOutput: %s", err, string(output))
+		err = clierror.New(err, output)
+		if errors.Is(err, clierror.ErrResourceNotFound) {
+			return fmt.Errorf("AKS cluster '%s' not found in resource group '%s', create it with 'spin azure cluster create': %w", clusterName, resourceGroup, err)
+		}
+		return fmt.Errorf("failed to get AKS cluster: %w", err)
 	}
 
 	return nil
@@ -159,9 +166,9 @@ func (s *Service) CheckWorkloadIdentity(ctx context.Context) (bool, error) {
 
 	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
 
-	output, err := cmd.CombinedOutput()
+	output, stderr, err := retry.Output(cmd)
 	if err != nil {
-		return false, fmt.Errorf("failed to check workload identity: %w\nOutput: %s", err, string(output))
+		return false, fmt.Errorf("failed to check workload identity: %w", clierror.New(err, stderr))
 	}
 
 	result := strings.TrimSpace(string(output))
@@ -190,9 +197,72 @@ func (s *Service) EnableWorkloadIdentity(ctx context.Context) error {
 
 	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
 
-	output, err := cmd.CombinedOutput()
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to enable workload identity: %w", clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// CheckAppRouting checks if the application routing add-on is enabled on the current cluster
+func (s *Service) CheckAppRouting(ctx context.Context) (bool, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return false, fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	cmd := exec.Command(
+		"az", "aks", "show",
+		"--resource-group", cfg.ResourceGroup,
+		"--name", cfg.ClusterName,
+		"--subscription", s.subscriptionID,
+		"--query", "ingressProfile.webAppRouting.enabled",
+		"--output", "tsv",
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return false, fmt.Errorf("failed to check application routing: %w", clierror.New(err, stderr))
+	}
+
+	result := strings.TrimSpace(string(output))
+	return result == "true", nil
+}
+
+// EnableAppRouting enables the application routing add-on on the current cluster
+func (s *Service) EnableAppRouting(ctx context.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	cmd := exec.Command(
+		"az", "aks", "approuting", "enable",
+		"--resource-group", cfg.ResourceGroup,
+		"--name", cfg.ClusterName,
+		"--subscription", s.subscriptionID,
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+	spinnerDone := runSpinner("enabling application routing...")
+
+	output, err := retry.CombinedOutput(cmd)
+
+	close(spinnerDone)
+
 	if err != nil {
-		return fmt.Errorf("failed to enable workload identity: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to enable application routing: %w", clierror.New(err, output))
 	}
 
 	return nil
@@ -220,42 +290,42 @@ func (s *Service) DeploySpinOperator(ctx context.Context) error {
 
 	fmt.Println("Executing command:", strings.Join(getCredsCmd.Args, " "))
 
-	output, err := getCredsCmd.CombinedOutput()
+	output, err := retry.CombinedOutput(getCredsCmd)
 	if err != nil {
-		return fmt.Errorf("failed to get Kubernetes credentials: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to get Kubernetes credentials: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Installing Spin Operator Custom Resource Definitions...")
 	crdsCmd := exec.Command(
 		"kubectl", "apply", "-f",
-		"https://github.com/spinkube/spin-operator/releases/download/v0.4.0/spin-operator.crds.yaml",
+		spinOperatorReleaseURL(cfg, "spin-operator.crds.yaml"),
 	)
 
-	output, err = crdsCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(crdsCmd)
 	if err != nil {
-		return fmt.Errorf("failed to install Spin Operator CRDs: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to install Spin Operator CRDs: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Installing Spin Operator Runtime Class...")
 	runtimeClassCmd := exec.Command(
 		"kubectl", "apply", "-f",
-		"https://github.com/spinkube/spin-operator/releases/download/v0.4.0/spin-operator.runtime-class.yaml",
+		spinOperatorReleaseURL(cfg, "spin-operator.runtime-class.yaml"),
 	)
 
-	output, err = runtimeClassCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(runtimeClassCmd)
 	if err != nil {
-		return fmt.Errorf("failed to install Spin Operator Runtime Class: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to install Spin Operator Runtime Class: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Installing cert-manager CRDs...")
 	certManagerCrdsCmd := exec.Command(
 		"kubectl", "apply", "-f",
-		"https://github.com/cert-manager/cert-manager/releases/download/v1.14.3/cert-manager.crds.yaml",
+		fmt.Sprintf("https://github.com/cert-manager/cert-manager/releases/download/%s/cert-manager.crds.yaml", withVPrefix(cfg.GetCertManagerVersion())),
 	)
 
-	output, err = certManagerCrdsCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(certManagerCrdsCmd)
 	if err != nil {
-		return fmt.Errorf("failed to install cert-manager CRDs: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to install cert-manager CRDs: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Adding Jetstack Helm repository...")
@@ -263,9 +333,9 @@ func (s *Service) DeploySpinOperator(ctx context.Context) error {
 		"helm", "repo", "add", "jetstack", "https://charts.jetstack.io",
 	)
 
-	output, err = addJetstackRepoCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(addJetstackRepoCmd)
 	if err != nil {
-		return fmt.Errorf("failed to add Jetstack Helm repository: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to add Jetstack Helm repository: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Updating Helm repositories...")
@@ -273,22 +343,22 @@ func (s *Service) DeploySpinOperator(ctx context.Context) error {
 		"helm", "repo", "update",
 	)
 
-	output, err = updateHelmRepoCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(updateHelmRepoCmd)
 	if err != nil {
-		return fmt.Errorf("failed to update Helm repositories: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to update Helm repositories: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Installing cert-manager...")
 	installCertManagerCmd := exec.Command(
-		"helm", "install", "cert-manager", "jetstack/cert-manager",
+		"helm", "upgrade", "--install", "cert-manager", "jetstack/cert-manager",
 		"--namespace", "cert-manager",
 		"--create-namespace",
-		"--version", "v1.14.3",
+		"--version", withVPrefix(cfg.GetCertManagerVersion()),
 	)
 
-	output, err = installCertManagerCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(installCertManagerCmd)
 	if err != nil {
-		return fmt.Errorf("failed to install cert-manager: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to install cert-manager: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Adding KWasm Helm repository...")
@@ -296,22 +366,22 @@ func (s *Service) DeploySpinOperator(ctx context.Context) error {
 		"helm", "repo", "add", "kwasm", "http://kwasm.sh/kwasm-operator/",
 	)
 
-	output, err = addKwasmRepoCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(addKwasmRepoCmd)
 	if err != nil {
-		return fmt.Errorf("failed to add KWasm Helm repository: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to add KWasm Helm repository: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Installing KWasm operator...")
 	installKwasmCmd := exec.Command(
-		"helm", "install", "kwasm-operator", "kwasm/kwasm-operator",
+		"helm", "upgrade", "--install", "kwasm-operator", "kwasm/kwasm-operator",
 		"--namespace", "kwasm",
 		"--create-namespace",
-		"--set", "kwasmOperator.installerImage=ghcr.io/spinkube/containerd-shim-spin/node-installer:v0.18.0",
+		"--set", "kwasmOperator.installerImage=ghcr.io/spinkube/containerd-shim-spin/node-installer:"+withVPrefix(cfg.GetShimVersion()),
 	)
 
-	output, err = installKwasmCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(installKwasmCmd)
 	if err != nil {
-		return fmt.Errorf("failed to install KWasm operator: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to install KWasm operator: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Provisioning nodes with KWasm...")
@@ -319,9 +389,9 @@ func (s *Service) DeploySpinOperator(ctx context.Context) error {
 		"kubectl", "annotate", "node", "--all", "kwasm.sh/kwasm-node=true",
 	)
 
-	output, err = annotateNodesCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(annotateNodesCmd)
 	if err != nil {
-		return fmt.Errorf("failed to annotate nodes for KWasm: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to annotate nodes for KWasm: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Waiting for KWasm operator to initialize nodes...")
@@ -329,35 +399,35 @@ func (s *Service) DeploySpinOperator(ctx context.Context) error {
 		"sleep", "30",
 	)
 
-	_, err = waitCmd.CombinedOutput()
+	_, err = retry.CombinedOutput(waitCmd)
 	if err != nil {
 		return fmt.Errorf("failed while waiting for KWasm initialization: %w", err)
 	}
 
 	fmt.Println("Installing Spin Operator...")
 	installSpinOpCmd := exec.Command(
-		"helm", "install", "spin-operator",
+		"helm", "upgrade", "--install", "spin-operator",
 		"--namespace", "spin-operator",
 		"--create-namespace",
-		"--version", "0.4.0",
+		"--version", strings.TrimPrefix(cfg.GetSpinOperatorVersion(), "v"),
 		"--wait",
 		"oci://ghcr.io/spinkube/charts/spin-operator",
 	)
 
-	output, err = installSpinOpCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(installSpinOpCmd)
 	if err != nil {
-		return fmt.Errorf("failed to install Spin Operator: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to install Spin Operator: %w", clierror.New(err, output))
 	}
 
 	fmt.Println("Applying shim executor configuration...")
 	shimExecutorCmd := exec.Command(
 		"kubectl", "apply", "-f",
-		"https://github.com/spinkube/spin-operator/releases/download/v0.4.0/spin-operator.shim-executor.yaml",
+		spinOperatorReleaseURL(cfg, "spin-operator.shim-executor.yaml"),
 	)
 
-	output, err = shimExecutorCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(shimExecutorCmd)
 	if err != nil {
-		return fmt.Errorf("failed to apply shim executor configuration: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to apply shim executor configuration: %w", clierror.New(err, output))
 	}
 
 	if err := config.SaveConfig(cfg); err != nil {
@@ -390,23 +460,23 @@ func (s *Service) CreateServiceAccount(ctx context.Context, identityName string)
 
 	fmt.Println("Executing command:", strings.Join(getCredsCmd.Args, " "))
 
-	output, err := getCredsCmd.CombinedOutput()
+	output, err := retry.CombinedOutput(getCredsCmd)
 	if err != nil {
-		return fmt.Errorf("failed to get Kubernetes credentials: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to get Kubernetes credentials: %w", clierror.New(err, output))
 	}
 
-	identityClientID, err := s.getIdentityClientID(identityName, cfg.ResourceGroup)
+	identityClientID, err := s.GetIdentityClientID(identityName, cfg.ResourceGroup)
 	if err != nil {
 		return fmt.Errorf("failed to get identity client ID: %w", err)
 	}
 
-	namespace := "default"
+	namespace := cfg.GetNamespace()
 
 	fmt.Printf("Checking if service account '%s' exists...\n", identityName)
 	checkCmd := exec.Command("kubectl", "get", "serviceaccount", identityName, "-n", namespace, "--ignore-not-found")
-	output, err = checkCmd.CombinedOutput()
+	output, err = retry.CombinedOutput(checkCmd)
 	if err != nil {
-		return fmt.Errorf("failed to check if service account exists: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to check if service account exists: %w", clierror.New(err, output))
 	}
 
 	if strings.Contains(string(output), identityName) {
@@ -424,6 +494,16 @@ metadata:
     azure.workload.identity/client-id: %s
 `, identityName, namespace, identityClientID)
 
+	// Namespaces other than the default one may not exist yet
+	if namespace != config.DefaultNamespace {
+		saYAML = fmt.Sprintf(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: %s
+---`, namespace) + saYAML
+	}
+
 	tempFile, err := os.CreateTemp("", "sa-*.yaml")
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
@@ -437,16 +517,17 @@ metadata:
 
 	fmt.Printf("Creating service account '%s'...\n", identityName)
 	cmd := exec.Command("kubectl", "apply", "-f", tempFile.Name())
-	output, err = cmd.CombinedOutput()
+	output, err = retry.CombinedOutput(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to create service account: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to create service account: %w", clierror.New(err, output))
 	}
 
 	fmt.Printf("Created service account '%s' in namespace '%s'\n", identityName, namespace)
 	return nil
 }
 
-func (s *Service) getIdentityClientID(name, resourceGroup string) (string, error) {
+// GetIdentityClientID returns the client ID of a managed identity
+func (s *Service) GetIdentityClientID(name, resourceGroup string) (string, error) {
 	if resourceGroup == "" {
 		cfg, err := config.LoadConfig()
 		if err != nil {
@@ -466,9 +547,9 @@ func (s *Service) getIdentityClientID(name, resourceGroup string) (string, error
 
 	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
 
-	output, err := cmd.CombinedOutput()
+	output, stderr, err := retry.Output(cmd)
 	if err != nil {
-		return "", fmt.Errorf("failed to get identity client ID: %w\nOutput: %s", err, string(output))
+		return "", fmt.Errorf("failed to get identity client ID: %w", clierror.New(err, stderr))
 	}
 
 	return strings.TrimSpace(string(output)), nil
@@ -495,12 +576,12 @@ func (s *Service) CreateIdentity(ctx context.Context, identityName string, resou
 
 	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
 
-	output, err := cmd.CombinedOutput()
+	output, err := retry.CombinedOutput(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to create managed identity: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to create managed identity: %w", clierror.New(err, output))
 	}
 
-	clientID, err := s.getIdentityClientID(identityName, resourceGroup)
+	clientID, err := s.GetIdentityClientID(identityName, resourceGroup)
 	if err != nil {
 		return fmt.Errorf("failed to get identity client ID: %w", err)
 	}
@@ -512,7 +593,7 @@ func (s *Service) CreateIdentity(ctx context.Context, identityName string, resou
 		}
 
 		fmt.Printf("Creating federated credential for identity '%s'...\n", identityName)
-		if err := s.createFederatedCredential(identityName, clientID, cfg.ClusterName, resourceGroup); err != nil {
+		if err := s.createFederatedCredential(identityName, clientID, cfg.ClusterName, resourceGroup, cfg.GetNamespace()); err != nil {
 			return fmt.Errorf("failed to create federated identity credential: %w", err)
 		}
 	}
@@ -528,7 +609,7 @@ func (s *Service) CreateIdentity(ctx context.Context, identityName string, resou
 
 // UseIdentity sets the current identity in the configuration
 func (s *Service) UseIdentity(ctx context.Context, identityName string, resourceGroup string, createServiceAccount bool) error {
-	clientID, err := s.getIdentityClientID(identityName, resourceGroup)
+	clientID, err := s.GetIdentityClientID(identityName, resourceGroup)
 	if err != nil {
 		return fmt.Errorf("failed to find managed identity '%s': %w", identityName, err)
 	}
@@ -550,7 +631,7 @@ func (s *Service) UseIdentity(ctx context.Context, identityName string, resource
 		}
 
 		fmt.Printf("Creating federated credential for identity '%s'...\n", identityName)
-		if err := s.createFederatedCredential(identityName, clientID, cfg.ClusterName, resourceGroup); err != nil {
+		if err := s.createFederatedCredential(identityName, clientID, cfg.ClusterName, resourceGroup, cfg.GetNamespace()); err != nil {
 			return fmt.Errorf("failed to create federated credential: %w", err)
 		}
 	}
@@ -566,16 +647,20 @@ func (s *Service) UseIdentity(ctx context.Context, identityName string, resource
 }
 
 // Create a federated identity credential for the managed identity
-func (s *Service) createFederatedCredential(identityName, clientID, clusterName, resourceGroup string) error {
+func (s *Service) createFederatedCredential(identityName, clientID, clusterName, resourceGroup, namespace string) error {
 	oidcURL, err := s.getClusterOIDCIssuerURL(clusterName, resourceGroup)
 	if err != nil {
 		return fmt.Errorf("failed to get cluster OIDC issuer URL: %w", err)
 	}
 
-	namespace := "default"
 	subject := fmt.Sprintf("system:serviceaccount:%s:%s", namespace, identityName)
 
 	credName := fmt.Sprintf("%s-federated-credential", identityName)
+	return s.createFederatedCredentialForSubject(credName, identityName, resourceGroup, oidcURL, subject)
+}
+
+// Create a federated identity credential trusting the given service account subject
+func (s *Service) createFederatedCredentialForSubject(credName, identityName, resourceGroup, oidcURL, subject string) error {
 	fmt.Printf("Creating federated identity credential '%s'...\n", credName)
 
 	cmd := exec.Command(
@@ -591,9 +676,9 @@ func (s *Service) createFederatedCredential(identityName, clientID, clusterName,
 
 	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
 
-	output, err := cmd.CombinedOutput()
+	output, err := retry.CombinedOutput(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to create federated identity credential: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to create federated identity credential: %w", clierror.New(err, output))
 	}
 
 	return nil
@@ -612,10 +697,20 @@ func (s *Service) getClusterOIDCIssuerURL(clusterName, resourceGroup string) (st
 
 	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
 
-	output, err := cmd.CombinedOutput()
+	output, stderr, err := retry.Output(cmd)
 	if err != nil {
-		return "", fmt.Errorf("failed to get cluster OIDC issuer URL: %w\nOutput: %s", err, string(output))
+		return "", fmt.Errorf("failed to get cluster OIDC issuer URL: %w", clierror.New(err, stderr))
 	}
 
 	return strings.TrimSpace(string(output)), nil
 }
+
+// spinOperatorReleaseURL returns the URL of a manifest released with the configured Spin Operator version
+func spinOperatorReleaseURL(cfg *config.Config, manifest string) string {
+	return fmt.Sprintf("https://github.com/spinkube/spin-operator/releases/download/%s/%s", withVPrefix(cfg.GetSpinOperatorVersion()), manifest)
+}
+
+// withVPrefix returns the version as used in release tags, which start with 'v'
+func withVPrefix(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
+}
@@ -0,0 +1,96 @@
+package aks
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/kube"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+const (
+	// ClusterIssuerName is the name of the cert-manager ClusterIssuer created by ConfigureTLS
+	ClusterIssuerName = "letsencrypt"
+
+	letsEncryptProductionServer = "https://acme-v02.api.letsencrypt.org/directory"
+	letsEncryptStagingServer    = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+	// AppRoutingIngressClass is the ingress class provided by the AKS application routing add-on
+	AppRoutingIngressClass = "webapprouting.kubernetes.azure.com"
+)
+
+// emailPattern matches the plain email addresses accepted for a Let's Encrypt account
+var emailPattern = regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)+$`)
+
+// validateEmail checks the email address before it is written into the ClusterIssuer manifest
+func validateEmail(email string) error {
+	if !emailPattern.MatchString(email) {
+		return fmt.Errorf("invalid email address '%s', expected an address such as me@example.com", email)
+	}
+
+	return nil
+}
+
+// ConfigureTLS creates a Let's Encrypt ClusterIssuer using the cert-manager installed with the Spin Operator
+func (s *Service) ConfigureTLS(ctx context.Context, email string, staging bool) error {
+	if err := validateEmail(email); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+	}
+
+	if err := kube.GetCredentials(s.subscriptionID, cfg.ClusterName, cfg.ResourceGroup); err != nil {
+		return err
+	}
+
+	checkCmd := exec.Command("kubectl", "get", "deployment", "cert-manager", "-n", "cert-manager", "--ignore-not-found", "-o", "name")
+	output, stderr, err := retry.Output(checkCmd)
+	if err != nil {
+		return fmt.Errorf("failed to check if cert-manager is installed: %w", clierror.New(err, stderr))
+	}
+
+	if strings.TrimSpace(string(output)) == "" {
+		return fmt.Errorf("cert-manager is not installed on the cluster, install it using 'spin azure cluster install-spin-operator'")
+	}
+
+	server := letsEncryptProductionServer
+	if staging {
+		server = letsEncryptStagingServer
+	}
+
+	issuerYAML := fmt.Sprintf(`
+apiVersion: cert-manager.io/v1
+kind: ClusterIssuer
+metadata:
+  name: %s
+spec:
+  acme:
+    server: %s
+    email: %s
+    privateKeySecretRef:
+      name: %s-account-key
+    solvers:
+    - http01:
+        ingress:
+          ingressClassName: %s
+`, ClusterIssuerName, server, email, ClusterIssuerName, AppRoutingIngressClass)
+
+	fmt.Printf("Creating ClusterIssuer '%s'...\n", ClusterIssuerName)
+	if err := kube.ApplyManifest(issuerYAML); err != nil {
+		return fmt.Errorf("failed to create ClusterIssuer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package aks
+
+import "testing"
+
+func TestValidateEmail(t *testing.T) {
+	valid := []string{"me@example.com", "first.last+certs@mail.example.co.uk"}
+	for _, email := range valid {
+		if err := validateEmail(email); err != nil {
+			t.Errorf("Expected email '%s' to be valid, got %v", email, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"me",
+		"me@example",
+		"me @example.com",
+		"me@example.com\n    server: https://attacker.example.com",
+		"me@example.com # comment",
+		`"me"@example.com`,
+		"Me <me@example.com>",
+	}
+	for _, email := range invalid {
+		if err := validateEmail(email); err == nil {
+			t.Errorf("Expected email '%s' to be rejected", email)
+		}
+	}
+}
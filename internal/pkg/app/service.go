@@ -0,0 +1,299 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/kube"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// appNameLabel is the label the Spin Operator puts on every pod of a SpinApp
+const appNameLabel = "core.spinoperator.dev/app-name"
+
+// Service provides operations for SpinApps deployed to the current AKS cluster
+type Service struct {
+	credential     azcore.TokenCredential
+	subscriptionID string
+}
+
+// App describes a SpinApp together with the identity it runs as
+type App struct {
+	Name             string      `json:"name"`
+	Namespace        string      `json:"namespace"`
+	Image            string      `json:"image"`
+	Executor         string      `json:"executor"`
+	Replicas         int         `json:"replicas"`
+	ReadyReplicas    int         `json:"readyReplicas"`
+	ServiceAccount   string      `json:"serviceAccount"`
+	IdentityName     string      `json:"identityName,omitempty"`
+	IdentityClientID string      `json:"identityClientId,omitempty"`
+	Conditions       []Condition `json:"conditions,omitempty"`
+}
+
+// Condition is a status condition reported by the Spin Operator
+type Condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type spinApp struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		Image    string `json:"image"`
+		Executor string `json:"executor"`
+		Replicas int    `json:"replicas"`
+		// EnableAutoscaling hands the replicas over to an HPA or KEDA ScaledObject
+		EnableAutoscaling bool `json:"enableAutoscaling"`
+	} `json:"spec"`
+	Status struct {
+		ReadyReplicas int         `json:"readyReplicas"`
+		Conditions    []Condition `json:"conditions"`
+	} `json:"status"`
+}
+
+type identity struct {
+	name     string
+	clientID string
+}
+
+type spinAppList struct {
+	Items []spinApp `json:"items"`
+}
+
+// NewService creates a new app service
+func NewService(credential azcore.TokenCredential, subscriptionID string) *Service {
+	return &Service{
+		credential:     credential,
+		subscriptionID: subscriptionID,
+	}
+}
+
+// List returns all SpinApps in the given namespace of the current cluster
+func (s *Service) List(ctx context.Context, namespace string) ([]App, error) {
+	if err := kube.UseCurrentCluster(s.subscriptionID); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("kubectl", "get", "spinapps", "-n", namespace, "-o", "json")
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SpinApps: %w", clierror.New(err, stderr))
+	}
+
+	var list spinAppList
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse SpinApps: %w", err)
+	}
+
+	identities := make(map[string]identity)
+	apps := make([]App, 0, len(list.Items))
+	for _, item := range list.Items {
+		app := newApp(item)
+		if err := s.resolveIdentity(&app, identities); err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+// Get returns the SpinApp with the given name
+func (s *Service) Get(ctx context.Context, name, namespace string) (*App, error) {
+	if err := kube.UseCurrentCluster(s.subscriptionID); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("kubectl", "get", "spinapp", name, "-n", namespace, "-o", "json")
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		err = clierror.New(err, stderr)
+		if errors.Is(err, clierror.ErrResourceNotFound) {
+			return nil, fmt.Errorf("SpinApp '%s' not found in namespace '%s', list the deployed apps with 'spin azure app list': %w", name, namespace, err)
+		}
+		return nil, fmt.Errorf("failed to get SpinApp '%s': %w", name, err)
+	}
+
+	var item spinApp
+	if err := json.Unmarshal(output, &item); err != nil {
+		return nil, fmt.Errorf("failed to parse SpinApp '%s': %w", name, err)
+	}
+
+	app := newApp(item)
+	if err := s.resolveIdentity(&app, make(map[string]identity)); err != nil {
+		return nil, err
+	}
+
+	return &app, nil
+}
+
+// Logs writes the logs of all pods of the SpinApp to out, streaming them if follow is set
+func (s *Service) Logs(ctx context.Context, name, namespace string, follow bool, out io.Writer) error {
+	if err := kube.UseCurrentCluster(s.subscriptionID); err != nil {
+		return err
+	}
+
+	args := []string{
+		"logs",
+		"-n", namespace,
+		"-l", fmt.Sprintf("%s=%s", appNameLabel, name),
+		"--all-containers",
+		"--prefix",
+	}
+	if follow {
+		args = append(args, "-f")
+	}
+
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to get logs for SpinApp '%s': %w", name, err)
+	}
+
+	return nil
+}
+
+// Scale sets the number of replicas of the SpinApp
+func (s *Service) Scale(ctx context.Context, name, namespace string, replicas int) error {
+	if err := kube.UseCurrentCluster(s.subscriptionID); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("kubectl", "get", "spinapp", name, "-n", namespace, "-o", "json")
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get SpinApp '%s': %w", name, clierror.New(err, stderr))
+	}
+
+	var item spinApp
+	if err := json.Unmarshal(output, &item); err != nil {
+		return fmt.Errorf("failed to parse SpinApp '%s': %w", name, err)
+	}
+
+	if err := checkScalable(item); err != nil {
+		return err
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas)
+	cmd = exec.Command("kubectl", "patch", "spinapp", name, "-n", namespace, "--type", "merge", "-p", patch)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, err = retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to scale SpinApp '%s': %w", name, clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// checkScalable returns an error for a SpinApp whose replicas are managed by an autoscaler, since the
+// Spin Operator rejects replicas together with enableAutoscaling
+func checkScalable(item spinApp) error {
+	if item.Spec.EnableAutoscaling {
+		return fmt.Errorf("SpinApp '%s' is autoscaled, change its replica range with 'spin azure deploy --autoscale min:max' "+
+			"or redeploy without --autoscale to set its replicas", item.Metadata.Name)
+	}
+
+	return nil
+}
+
+// Delete removes the SpinApp from the cluster
+func (s *Service) Delete(ctx context.Context, name, namespace string) error {
+	if err := kube.UseCurrentCluster(s.subscriptionID); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("kubectl", "delete", "spinapp", name, "-n", namespace)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to delete SpinApp '%s': %w", name, clierror.New(err, output))
+	}
+
+	return nil
+}
+
+func newApp(item spinApp) App {
+	return App{
+		Name:          item.Metadata.Name,
+		Namespace:     item.Metadata.Namespace,
+		Image:         item.Spec.Image,
+		Executor:      item.Spec.Executor,
+		Replicas:      item.Spec.Replicas,
+		ReadyReplicas: item.Status.ReadyReplicas,
+		Conditions:    item.Status.Conditions,
+	}
+}
+
+// resolveIdentity looks up the service account of the app's deployment and the
+// managed identity federated with it. Results are cached per service account.
+func (s *Service) resolveIdentity(app *App, cache map[string]identity) error {
+	cmd := exec.Command(
+		"kubectl", "get", "deployment", app.Name,
+		"-n", app.Namespace,
+		"--ignore-not-found",
+		"-o", "jsonpath={.spec.template.spec.serviceAccountName}",
+	)
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get deployment for SpinApp '%s': %w", app.Name, clierror.New(err, stderr))
+	}
+
+	app.ServiceAccount = strings.TrimSpace(string(output))
+	if app.ServiceAccount == "" {
+		app.ServiceAccount = "default"
+	}
+
+	key := app.Namespace + "/" + app.ServiceAccount
+	if cached, ok := cache[key]; ok {
+		app.IdentityName, app.IdentityClientID = cached.name, cached.clientID
+		return nil
+	}
+
+	cmd = exec.Command(
+		"kubectl", "get", "serviceaccount", app.ServiceAccount,
+		"-n", app.Namespace,
+		"--ignore-not-found",
+		"-o", `jsonpath={.metadata.annotations.azure\.workload\.identity/client-id}`,
+	)
+	output, stderr, err = retry.Output(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get service account '%s': %w", app.ServiceAccount, clierror.New(err, stderr))
+	}
+
+	app.IdentityClientID = strings.TrimSpace(string(output))
+	if app.IdentityClientID != "" {
+		cmd = exec.Command(
+			"az", "identity", "list",
+			"--subscription", s.subscriptionID,
+			"--query", fmt.Sprintf("[?clientId=='%s'].name | [0]", app.IdentityClientID),
+			"--output", "tsv",
+		)
+		output, stderr, err = retry.Output(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to look up identity with client ID '%s': %w", app.IdentityClientID, clierror.New(err, stderr))
+		}
+		app.IdentityName = strings.TrimSpace(string(output))
+	}
+
+	cache[key] = identity{name: app.IdentityName, clientID: app.IdentityClientID}
+	return nil
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCheckScalable(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		scalable bool
+	}{
+		{
+			name:     "fixed replicas",
+			manifest: `{"metadata":{"name":"hello","namespace":"default"},"spec":{"image":"ghcr.io/hello:1","replicas":2}}`,
+			scalable: true,
+		},
+		{
+			name:     "autoscaling disabled",
+			manifest: `{"metadata":{"name":"hello","namespace":"default"},"spec":{"image":"ghcr.io/hello:1","enableAutoscaling":false}}`,
+			scalable: true,
+		},
+		{
+			name:     "autoscaled",
+			manifest: `{"metadata":{"name":"hello","namespace":"default"},"spec":{"image":"ghcr.io/hello:1","enableAutoscaling":true}}`,
+			scalable: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var item spinApp
+			if err := json.Unmarshal([]byte(test.manifest), &item); err != nil {
+				t.Fatalf("Failed to parse SpinApp: %v", err)
+			}
+
+			err := checkScalable(item)
+			if test.scalable && err != nil {
+				t.Errorf("Expected SpinApp to be scalable, got %v", err)
+			}
+			if !test.scalable {
+				if err == nil {
+					t.Fatal("Expected autoscaled SpinApp to be rejected")
+				}
+				if !strings.Contains(err.Error(), "--autoscale") {
+					t.Errorf("Expected error to point to deploy --autoscale, got %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package bind
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+type ACRService struct {
+	service
+}
+
+func NewACRService(credential azcore.TokenCredential, subscriptionID string) *ACRService {
+	return &ACRService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// BindACR grants AcrPull on the container registry to the given principal, typically the
+// kubelet identity of an AKS cluster, which is what pulls SpinApp images
+func (s *ACRService) BindACR(ctx context.Context, registry, resourceGroup, principalID string) error {
+	registryID, err := s.getResourceID(fmt.Sprintf("container registry '%s'", registry), resourceGroup,
+		"acr", "show", "--name", registry)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Assigning role 'AcrPull' on container registry '%s'...\n", registry)
+	if err := s.assignRole(principalID, "AcrPull", registryID); err != nil {
+		return fmt.Errorf("failed to assign role to container registry: %w", err)
+	}
+
+	if err := s.waitForRoleAssignment(ctx, principalID, "AcrPull", registryID); err != nil {
+		return err
+	}
+
+	return nil
+}
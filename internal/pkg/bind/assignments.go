@@ -0,0 +1,447 @@
+package bind
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+const (
+	AssignmentTypeRBAC     = "Azure RBAC"
+	AssignmentTypeCosmosDB = "CosmosDB SQL"
+	AssignmentTypeRedis    = "Redis access policy"
+)
+
+// revocableResources maps the services whose access is granted with Azure RBAC to the az command
+// showing the resource the roles were assigned on
+var revocableResources = map[string][]string{
+	"storage":    {"storage", "account", "show"},
+	"servicebus": {"servicebus", "namespace", "show"},
+	"eventhubs":  {"eventhubs", "namespace", "show"},
+	"openai":     {"cognitiveservices", "account", "show"},
+	"keyvault":   {"keyvault", "show"},
+}
+
+// RoleAssignment is a role held by an identity, either an Azure RBAC role assignment or a data-plane
+// assignment of a service that does not use Azure RBAC for data access
+type RoleAssignment struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Role  string `json:"role"`
+	Scope string `json:"scope"`
+}
+
+type AssignmentService struct {
+	service
+}
+
+type cosmosDBRoleAssignment struct {
+	ID               string `json:"id"`
+	PrincipalID      string `json:"principalId"`
+	RoleDefinitionID string `json:"roleDefinitionId"`
+	Scope            string `json:"scope"`
+}
+
+type redisAccessPolicyAssignment struct {
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	ObjectID          string `json:"objectId"`
+	AccessPolicyName  string `json:"accessPolicyName"`
+	ProvisioningState string `json:"provisioningState"`
+}
+
+type accountReference struct {
+	Name          string `json:"name"`
+	ResourceGroup string `json:"resourceGroup"`
+}
+
+func NewAssignmentService(credential azcore.TokenCredential, subscriptionID string) *AssignmentService {
+	return &AssignmentService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// ListAssignments returns the Azure RBAC role assignments of the identity in the subscription, along
+// with its CosmosDB SQL role assignments and Redis access policy assignments. When resourceGroup is set,
+// only assignments in that resource group are returned. Accounts and caches whose assignments cannot be
+// listed are skipped, and the errors are returned alongside the assignments that could be listed.
+func (s *AssignmentService) ListAssignments(ctx context.Context, identityName, identityResourceGroup, resourceGroup string) ([]RoleAssignment, []error, error) {
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rbacAssignments, err := s.listRoleAssignments(identityPrincipalID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var assignments []RoleAssignment
+	for _, a := range rbacAssignments {
+		if resourceGroup == "" || withinScope(a.Scope, s.resourceGroupScope(resourceGroup)) {
+			assignments = append(assignments, a)
+		}
+	}
+
+	var failures []error
+	cosmosDBAccounts, err := s.listAccounts("failed to list CosmosDB accounts", "cosmosdb", resourceGroup)
+	if err != nil {
+		failures = append(failures, err)
+	}
+	for _, account := range cosmosDBAccounts {
+		cosmosDBAssignments, err := s.listCosmosDBRoleAssignments(account.Name, account.ResourceGroup, identityPrincipalID)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("CosmosDB account '%s': %w", account.Name, err))
+			continue
+		}
+		for _, a := range cosmosDBAssignments {
+			assignments = append(assignments, RoleAssignment{
+				ID:    a.ID,
+				Type:  AssignmentTypeCosmosDB,
+				Role:  cosmosDBRoleName(a.RoleDefinitionID),
+				Scope: a.Scope,
+			})
+		}
+	}
+
+	redisCaches, err := s.listAccounts("failed to list Redis caches", "redis", resourceGroup)
+	if err != nil {
+		failures = append(failures, err)
+	}
+	for _, cache := range redisCaches {
+		redisAssignments, err := s.listRedisAccessPolicyAssignments(cache.Name, cache.ResourceGroup, identityPrincipalID)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("Redis cache '%s': %w", cache.Name, err))
+			continue
+		}
+		for _, a := range redisAssignments {
+			assignments = append(assignments, RoleAssignment{
+				ID:    a.ID,
+				Type:  AssignmentTypeRedis,
+				Role:  a.AccessPolicyName,
+				Scope: strings.TrimSuffix(a.ID, "/accessPolicyAssignments/"+a.Name),
+			})
+		}
+	}
+
+	return assignments, failures, nil
+}
+
+// listAccounts lists the resources of an az command group such as cosmosdb or redis, in the resource
+// group when it is set
+func (s *AssignmentService) listAccounts(description, group, resourceGroup string) ([]accountReference, error) {
+	args := []string{group, "list", "--query", "[].{name:name, resourceGroup:resourceGroup}"}
+	if resourceGroup != "" {
+		args = append(args, "--resource-group", resourceGroup)
+	}
+
+	var accounts []accountReference
+	if err := s.runJSON(&accounts, description, args...); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+func (s *AssignmentService) resourceGroupScope(resourceGroup string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", s.subscriptionID, resourceGroup)
+}
+
+// RevokeScope deletes the Azure RBAC role assignments of the role held by the identity at the scope and below it.
+// The role is required, so that assignments made outside of spin azure are not deleted by accident.
+func (s *AssignmentService) RevokeScope(ctx context.Context, scope, role, identityName, identityResourceGroup string) (int, error) {
+	if role == "" {
+		return 0, fmt.Errorf("a role is required to revoke roles at a scope")
+	}
+
+	return s.revokeRoles(scope, []string{role}, identityName, identityResourceGroup)
+}
+
+// revokeRoles deletes the Azure RBAC role assignments of the roles held by the identity at the scope and below it
+func (s *AssignmentService) revokeRoles(scope string, roles []string, identityName, identityResourceGroup string) (int, error) {
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.revokePrincipalScope(identityPrincipalID, scope, roles)
+}
+
+// revokePrincipalScope deletes the Azure RBAC role assignments of the roles held by the principal at the scope and below it
+func (s *AssignmentService) revokePrincipalScope(principalID, scope string, roles []string) (int, error) {
+	assignments, err := s.listRoleAssignments(principalID)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []string
+	for _, a := range assignments {
+		if withinScope(a.Scope, scope) && containsRole(roles, a.Role) {
+			fmt.Printf("Revoking role '%s' at scope '%s'...\n", a.Role, a.Scope)
+			ids = append(ids, a.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	args := append([]string{"role", "assignment", "delete", "--ids"}, ids...)
+	if _, err := s.run("failed to delete role assignments", args...); err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+// bindingRoles returns the built-in roles assign-role grants on a service in revocableResources,
+// which are the roles revoked when no role is given
+func bindingRoles(serviceName string) []string {
+	var roles []string
+	switch serviceName {
+	case "storage":
+		for _, access := range storageRoles {
+			for _, role := range access {
+				roles = append(roles, role)
+			}
+		}
+	case "servicebus":
+		for _, suffix := range messagingRoles {
+			roles = append(roles, "Azure Service Bus "+suffix)
+		}
+	case "eventhubs":
+		for _, suffix := range messagingRoles {
+			roles = append(roles, "Azure Event Hubs "+suffix)
+		}
+	case "keyvault":
+		for role := range keyVaultAccessPolicies {
+			roles = append(roles, role)
+		}
+	case "openai":
+		roles = append(roles, DefaultOpenAIRole, "Cognitive Services OpenAI Contributor")
+	}
+	sort.Strings(roles)
+
+	return roles
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RevokeResource deletes the Azure RBAC role assignments of the identity on a resource of one of
+// the services in revocableResources, including those on its containers, queues, topics or event hubs.
+// Only assignments of role are deleted, or of the roles assign-role grants on the service when role is empty.
+// Key Vaults using access policies have the identity's access policy removed instead.
+func (s *AssignmentService) RevokeResource(ctx context.Context, serviceName, name, resourceGroup, role, identityName, identityResourceGroup string) (int, error) {
+	showArgs, ok := revocableResources[serviceName]
+	if !ok {
+		return 0, fmt.Errorf("unsupported service '%s'", serviceName)
+	}
+
+	roles := bindingRoles(serviceName)
+	if role != "" {
+		roles = []string{role}
+	}
+
+	if serviceName == "keyvault" {
+		keyVaultService := &KeyVaultService{service: s.service}
+		vault, err := keyVaultService.getKeyVault(name, resourceGroup)
+		if err != nil {
+			return 0, err
+		}
+
+		if !vault.EnableRbacAuthorization {
+			identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+			if err != nil {
+				return 0, err
+			}
+
+			fmt.Printf("Key Vault '%s' uses access policies, removing the access policy of identity '%s'...\n", name, identityName)
+			if _, err := s.run("failed to delete Key Vault access policy",
+				"keyvault", "delete-policy",
+				"--name", name,
+				"--resource-group", resourceGroup,
+				"--object-id", identityPrincipalID,
+				"--subscription", s.subscriptionID,
+			); err != nil {
+				return 0, err
+			}
+			return 1, nil
+		}
+
+		return s.revokeRoles(vault.ID, roles, identityName, identityResourceGroup)
+	}
+
+	args := append(append([]string{}, showArgs...), "--name", name)
+	scope, err := s.getResourceID(fmt.Sprintf("%s '%s'", serviceName, name), resourceGroup, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.revokeRoles(scope, roles, identityName, identityResourceGroup)
+}
+
+// RevokeCosmosDB deletes the CosmosDB SQL role assignments of the identity on the account
+func (s *AssignmentService) RevokeCosmosDB(ctx context.Context, name, resourceGroup, identityName, identityResourceGroup string) (int, error) {
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return 0, err
+	}
+
+	assignments, err := s.listCosmosDBRoleAssignments(name, resourceGroup, identityPrincipalID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range assignments {
+		fmt.Printf("Revoking CosmosDB role '%s' at scope '%s'...\n", cosmosDBRoleName(a.RoleDefinitionID), a.Scope)
+		if _, err := s.run("failed to delete CosmosDB role assignment",
+			"cosmosdb", "sql", "role", "assignment", "delete",
+			"--account-name", name,
+			"--resource-group", resourceGroup,
+			"--role-assignment-id", path.Base(a.ID),
+			"--subscription", s.subscriptionID,
+			"--yes",
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(assignments), nil
+}
+
+// RevokeRedis deletes the Redis access policy assignments of the identity on the cache
+func (s *AssignmentService) RevokeRedis(ctx context.Context, name, resourceGroup, identityName, identityResourceGroup string) (int, error) {
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return 0, err
+	}
+
+	assignments, err := s.listRedisAccessPolicyAssignments(name, resourceGroup, identityPrincipalID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range assignments {
+		fmt.Printf("Revoking Redis access policy '%s'...\n", a.AccessPolicyName)
+		if _, err := s.run("failed to delete Redis access policy assignment",
+			"redis", "access-policy-assignment", "delete",
+			"--name", a.Name,
+			"--redis-cache-name", name,
+			"--resource-group", resourceGroup,
+			"--subscription", s.subscriptionID,
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(assignments), nil
+}
+
+// RevokeACR deletes the AcrPull role assignment of the principal, typically the kubelet identity of
+// an AKS cluster, on the container registry
+func (s *AssignmentService) RevokeACR(ctx context.Context, registry, resourceGroup, principalID string) (int, error) {
+	registryID, err := s.getResourceID(fmt.Sprintf("container registry '%s'", registry), resourceGroup,
+		"acr", "show", "--name", registry)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.revokePrincipalScope(principalID, registryID, []string{"AcrPull"})
+}
+
+// RevokePostgres removes the identity from an Azure Database for PostgreSQL Flexible Server. With admin, the
+// identity is removed from the Microsoft Entra administrators, otherwise its database role is dropped by the
+// signed-in Entra administrator.
+func (s *AssignmentService) RevokePostgres(ctx context.Context, serverName, resourceGroup, database string, admin bool, identityName, identityResourceGroup string) (int, error) {
+	postgresService := &PostgresService{service: s.service}
+	server, err := postgresService.getPostgresServer(serverName, resourceGroup)
+	if err != nil {
+		return 0, err
+	}
+
+	if !admin {
+		return postgresService.dropDatabaseRole(server.FullyQualifiedDomainName, database, identityName)
+	}
+
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return 0, err
+	}
+
+	existing, err := s.run("failed to list Microsoft Entra administrators",
+		"postgres", "flexible-server", "ad-admin", "list",
+		"--server-name", serverName,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", fmt.Sprintf("[?objectId=='%s'].objectId", identityPrincipalID),
+		"--output", "tsv",
+	)
+	if err != nil {
+		return 0, err
+	}
+	if existing == "" {
+		return 0, nil
+	}
+
+	fmt.Printf("Removing identity '%s' from the Microsoft Entra administrators...\n", identityName)
+	if _, err := s.run("failed to remove Microsoft Entra administrator",
+		"postgres", "flexible-server", "ad-admin", "delete",
+		"--server-name", serverName,
+		"--resource-group", resourceGroup,
+		"--object-id", identityPrincipalID,
+		"--subscription", s.subscriptionID,
+		"--yes",
+	); err != nil {
+		return 0, err
+	}
+
+	return 1, nil
+}
+
+func (s *AssignmentService) listRoleAssignments(identityPrincipalID string) ([]RoleAssignment, error) {
+	var assignments []RoleAssignment
+	if err := s.runJSON(&assignments, "failed to list role assignments",
+		"role", "assignment", "list",
+		"--assignee", identityPrincipalID,
+		"--all",
+		"--query", fmt.Sprintf("[].{id:id, type:'%s', role:roleDefinitionName, scope:scope}", AssignmentTypeRBAC),
+	); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+// withinScope reports whether an assignment scope is the given scope or below it
+func withinScope(assignmentScope, scope string) bool {
+	assignmentScope = strings.ToLower(strings.TrimSuffix(assignmentScope, "/"))
+	scope = strings.ToLower(strings.TrimSuffix(scope, "/"))
+
+	return assignmentScope == scope || strings.HasPrefix(assignmentScope, scope+"/")
+}
+
+// cosmosDBRoleName returns the access level of a built-in CosmosDB role, or the ID of a custom role definition
+func cosmosDBRoleName(roleDefinitionID string) string {
+	for name, guid := range cosmosDBBuiltInRoles {
+		if strings.EqualFold(path.Base(roleDefinitionID), guid) {
+			return name
+		}
+	}
+
+	return roleDefinitionID
+}
@@ -0,0 +1,59 @@
+package bind
+
+import "testing"
+
+func TestWithinScope(t *testing.T) {
+	account := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/mystorage"
+
+	tests := []struct {
+		scope    string
+		expected bool
+	}{
+		{account, true},
+		{account + "/blobServices/default/containers/uploads", true},
+		{"/subscriptions/sub/resourceGroups/RG/providers/Microsoft.Storage/storageAccounts/MyStorage", true},
+		{account + "2", false},
+		{"/subscriptions/sub/resourceGroups/rg", false},
+	}
+
+	for _, test := range tests {
+		if within := withinScope(test.scope, account); within != test.expected {
+			t.Errorf("Expected withinScope('%s') to be %v, got %v", test.scope, test.expected, within)
+		}
+	}
+}
+
+func TestCosmosDBRoleName(t *testing.T) {
+	if name := cosmosDBRoleName("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.DocumentDB/databaseAccounts/cosmos/sqlRoleDefinitions/00000000-0000-0000-0000-000000000001"); name != "reader" {
+		t.Errorf("Expected 'reader', got '%s'", name)
+	}
+
+	custom := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.DocumentDB/databaseAccounts/cosmos/sqlRoleDefinitions/custom"
+	if name := cosmosDBRoleName(custom); name != custom {
+		t.Errorf("Expected custom role definition ID to be returned unchanged, got '%s'", name)
+	}
+}
+
+func TestBindingRoles(t *testing.T) {
+	tests := map[string]string{
+		"storage":    "Storage Blob Data Contributor",
+		"servicebus": "Azure Service Bus Data Receiver",
+		"eventhubs":  "Azure Event Hubs Data Owner",
+		"keyvault":   "Key Vault Secrets User",
+		"openai":     "Cognitive Services OpenAI User",
+	}
+
+	for serviceName, role := range tests {
+		roles := bindingRoles(serviceName)
+		if !containsRole(roles, role) {
+			t.Errorf("Expected roles of '%s' to contain '%s', got %v", serviceName, role, roles)
+		}
+		if containsRole(roles, "Owner") || containsRole(roles, "Contributor") {
+			t.Errorf("Expected roles of '%s' not to contain general roles, got %v", serviceName, roles)
+		}
+	}
+
+	if roles := bindingRoles("generic"); len(roles) != 0 {
+		t.Errorf("Expected no roles for an unknown service, got %v", roles)
+	}
+}
@@ -2,39 +2,221 @@ package bind
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
 )
 
+// cosmosDBBuiltInRoles maps the built-in Cosmos DB data-plane roles to their definition GUIDs
+var cosmosDBBuiltInRoles = map[string]string{
+	"reader":      "00000000-0000-0000-0000-000000000001",
+	"contributor": "00000000-0000-0000-0000-000000000002",
+}
+
+const cosmosDBDataActionPrefix = "Microsoft.DocumentDB/databaseAccounts/"
+
+const (
+	// DefaultCosmosDBDatabase and DefaultCosmosDBContainer are created for Spin's key-value store
+	// when no database or container is given
+	DefaultCosmosDBDatabase  = "spin"
+	DefaultCosmosDBContainer = "kv"
+
+	// cosmosDBKeyValuePartitionKey is the partition key Spin's key-value store expects on its container
+	cosmosDBKeyValuePartitionKey = "/id"
+)
+
+// CosmosDBAccount describes a CosmosDB account created for a Spin key-value store
+type CosmosDBAccount struct {
+	Endpoint  string
+	Database  string
+	Container string
+}
+
+// CosmosDBAccess describes the data-plane access granted on a CosmosDB account
+type CosmosDBAccess struct {
+	// Role is reader, contributor, the GUID or ID of a SQL role definition, or the name of the
+	// custom role to create when DataActions is set
+	Role string
+	// Database and Container narrow the scope of the assignment, by default it covers the whole account
+	Database  string
+	Container string
+	// DataActions creates or updates a custom SQL role definition named Role with these data actions
+	DataActions []string
+}
+
 type CosmosDBService struct {
-	credential     azcore.TokenCredential
-	subscriptionID string
+	service
 }
 
 func NewCosmosDBService(credential azcore.TokenCredential, subscriptionID string) *CosmosDBService {
 	return &CosmosDBService{
-		credential:     credential,
-		subscriptionID: subscriptionID,
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
 	}
 }
 
-func (s *CosmosDBService) BindCosmosDB(ctx context.Context, name, resourceGroup, identityName, identityResourceGroup string) error {
+// CreateCosmosDB creates a serverless NoSQL account with local (key) authentication disabled, unless it
+// already exists, and the database and container used by Spin's key-value store.
+// An existing account keeps its authentication settings, see DisableLocalAuth.
+func (s *CosmosDBService) CreateCosmosDB(ctx context.Context, name, resourceGroup, location, database, container string) (*CosmosDBAccount, error) {
+	if database == "" {
+		database = DefaultCosmosDBDatabase
+	}
+	if container == "" {
+		container = DefaultCosmosDBContainer
+	}
+
+	accountID, err := s.getCosmosDBResourceID(name, resourceGroup)
+	switch {
+	case errors.Is(err, clierror.ErrResourceNotFound):
+		if location == "" {
+			location, err = s.getResourceGroupLocation(resourceGroup)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		fmt.Printf("Creating serverless CosmosDB account '%s' in '%s'...\n", name, location)
+		accountID, err = s.run("failed to create CosmosDB account",
+			"cosmosdb", "create",
+			"--name", name,
+			"--resource-group", resourceGroup,
+			"--kind", "GlobalDocumentDB",
+			"--capabilities", "EnableServerless",
+			"--locations", fmt.Sprintf("regionName=%s", location),
+			"--subscription", s.subscriptionID,
+			"--query", "id",
+			"--output", "tsv",
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.disableLocalAuth(accountID); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		fmt.Printf("CosmosDB account '%s' already exists, its authentication settings are left unchanged\n", name)
+	}
+
+	fmt.Printf("Creating database '%s'...\n", database)
+	if _, err := s.run("failed to create CosmosDB database",
+		"cosmosdb", "sql", "database", "create",
+		"--account-name", name,
+		"--resource-group", resourceGroup,
+		"--name", database,
+		"--subscription", s.subscriptionID,
+	); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Creating container '%s'...\n", container)
+	if _, err := s.run("failed to create CosmosDB container",
+		"cosmosdb", "sql", "container", "create",
+		"--account-name", name,
+		"--resource-group", resourceGroup,
+		"--database-name", database,
+		"--name", container,
+		"--partition-key-path", cosmosDBKeyValuePartitionKey,
+		"--subscription", s.subscriptionID,
+	); err != nil {
+		return nil, err
+	}
+
+	endpoint, err := s.getCosmosDBEndpoint(name, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CosmosDBAccount{
+		Endpoint:  endpoint,
+		Database:  database,
+		Container: container,
+	}, nil
+}
+
+// DisableLocalAuth disables local (key) authentication on an existing account, so that it can only
+// be accessed with Microsoft Entra identities. Clients still using the account keys stop working.
+func (s *CosmosDBService) DisableLocalAuth(ctx context.Context, name, resourceGroup string) error {
+	accountID, err := s.getCosmosDBResourceID(name, resourceGroup)
+	if err != nil {
+		return err
+	}
+
+	return s.disableLocalAuth(accountID)
+}
+
+func (s *CosmosDBService) disableLocalAuth(accountID string) error {
+	fmt.Println("Disabling local (key) authentication...")
+	if _, err := s.run("failed to disable local authentication on CosmosDB account",
+		"resource", "update",
+		"--ids", accountID,
+		"--set", "properties.disableLocalAuth=true",
+		"--latest-include-preview",
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BindCosmosDB assigns a Cosmos DB SQL role to the identity on the account, or on a single database or container
+func (s *CosmosDBService) BindCosmosDB(ctx context.Context, name, resourceGroup string, access CosmosDBAccess, identityName, identityResourceGroup string) error {
+	if access.Container != "" && access.Database == "" {
+		return fmt.Errorf("--container requires --database")
+	}
+
 	if err := s.validateCosmosDBAccount(name, resourceGroup); err != nil {
 		return err
 	}
 
+	var roleDefinitionID string
+	var err error
+	if len(access.DataActions) > 0 {
+		roleDefinitionID, err = s.createCosmosDBRoleDefinition(name, resourceGroup, access.Role, access.DataActions)
+	} else {
+		roleDefinitionID, err = cosmosDBRoleDefinitionID(s.subscriptionID, resourceGroup, name, access.Role)
+	}
+	if err != nil {
+		return err
+	}
+
 	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
 	if err != nil {
 		return err
 	}
 
-	if err := s.assignRoleToCosmosDB(identityPrincipalID, name, resourceGroup); err != nil {
+	if err := s.assignRoleToCosmosDB(identityPrincipalID, name, resourceGroup, roleDefinitionID, access.Database, access.Container); err != nil {
 		return err
 	}
 
+	if s.waitTimeout > 0 {
+		endpoint, err := s.getCosmosDBEndpoint(name, resourceGroup)
+		if err != nil {
+			return err
+		}
+
+		if err := s.waitForDataPlane(ctx, identityName, dataPlaneProbe{
+			audience: "https://cosmos.azure.com",
+			url:      strings.TrimSuffix(endpoint, "/") + cosmosDBProbePath(access.Database, access.Container),
+			headers:  map[string]string{"x-ms-version": "2018-12-31"},
+			cosmosDB: true,
+		}); err != nil {
+			return err
+		}
+	}
+
 	fmt.Printf("Successfully bound CosmosDB '%s' to identity '%s'\n", name, identityName)
 
 	return nil
@@ -47,11 +229,11 @@ func (s *CosmosDBService) validateCosmosDBAccount(name, resourceGroup string) er
 		"--subscription", s.subscriptionID,
 	)
 
-	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+	printCommand(cmd)
 
-	output, err := cmd.CombinedOutput()
+	output, err := retry.CombinedOutput(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to check if CosmosDB exists: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to check if CosmosDB exists: %w", clierror.New(err, output))
 	}
 
 	cmd = exec.Command(
@@ -61,60 +243,60 @@ func (s *CosmosDBService) validateCosmosDBAccount(name, resourceGroup string) er
 		"--subscription", s.subscriptionID,
 	)
 
-	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+	printCommand(cmd)
 
-	output, err = cmd.CombinedOutput()
+	output, err = retry.CombinedOutput(cmd)
 	if err != nil {
-		return fmt.Errorf("CosmosDB '%s' not found in resource group '%s': %w\nOutput: %s",
-			name, resourceGroup, err, string(output))
+		return fmt.Errorf("CosmosDB '%s' not found in resource group '%s': %w",
+			name, resourceGroup, clierror.New(err, output))
 	}
 
 	return nil
 }
 
-func (s *CosmosDBService) getIdentityPrincipalID(name, resourceGroup string) (string, error) {
-	cmd := exec.Command(
-		"az", "identity", "show",
-		"--name", name,
-		"--resource-group", resourceGroup,
-		"--subscription", s.subscriptionID,
-		"--query", "principalId",
-		"--output", "tsv",
-	)
+func (s *CosmosDBService) assignRoleToCosmosDB(identityPrincipalID, cosmosDBName, resourceGroup, roleDefinitionID, database, container string) error {
+	cosmosDBResourceID, err := s.getCosmosDBResourceID(cosmosDBName, resourceGroup)
+	if err != nil {
+		return err
+	}
 
-	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+	scope := cosmosDBScope(cosmosDBResourceID, database, container)
 
-	output, err := cmd.CombinedOutput()
+	existing, err := s.listCosmosDBRoleAssignments(cosmosDBName, resourceGroup, identityPrincipalID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get identity principal ID: %w\nOutput: %s", err, string(output))
+		return err
+	}
+	for _, a := range existing {
+		if strings.EqualFold(a.RoleDefinitionID, roleDefinitionID) && strings.EqualFold(a.Scope, scope) {
+			fmt.Printf("Role definition '%s' is already assigned at scope '%s'\n", roleDefinitionID, scope)
+			return nil
+		}
 	}
 
-	return strings.TrimSpace(string(output)), nil
-}
+	fmt.Printf("Assigning role definition '%s' at scope '%s'...\n", roleDefinitionID, scope)
 
-func (s *CosmosDBService) assignRoleToCosmosDB(identityPrincipalID, cosmosDBName, resourceGroup string) error {
-	cosmosDBResourceID, err := s.getCosmosDBResourceID(cosmosDBName, resourceGroup)
+	// A fixed assignment ID makes retrying the create idempotent
+	assignmentID, err := newGUID()
 	if err != nil {
 		return err
 	}
 
-	roleDefinitionID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.DocumentDB/databaseAccounts/%s/sqlRoleDefinitions/00000000-0000-0000-0000-000000000002", s.subscriptionID, resourceGroup, cosmosDBName)
-
 	cmd := exec.Command(
 		"az", "cosmosdb", "sql", "role", "assignment", "create",
 		"--account-name", cosmosDBName,
 		"--resource-group", resourceGroup,
+		"--role-assignment-id", assignmentID,
 		"--role-definition-id", roleDefinitionID,
 		"--principal-id", identityPrincipalID,
-		"--scope", cosmosDBResourceID,
+		"--scope", scope,
 		"--subscription", s.subscriptionID,
 	)
 
-	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+	printCommand(cmd)
 
-	output, err := cmd.CombinedOutput()
+	output, err := retry.CombinedOutput(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to assign role to CosmosDB: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to assign role to CosmosDB: %w", clierror.New(err, output))
 	}
 
 	return nil
@@ -130,10 +312,189 @@ func (s *CosmosDBService) getCosmosDBResourceID(name, resourceGroup string) (str
 		"--output", "tsv",
 	)
 
-	output, err := cmd.CombinedOutput()
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to get CosmosDB resource ID: %w", clierror.New(err, stderr))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// createCosmosDBRoleDefinition creates a custom SQL role definition with the given data actions, or
+// updates the definition with the same name, and returns its ID
+func (s *CosmosDBService) createCosmosDBRoleDefinition(cosmosDBName, resourceGroup, roleName string, dataActions []string) (string, error) {
+	if _, builtIn := cosmosDBBuiltInRoles[roleName]; builtIn || roleName == "" {
+		return "", fmt.Errorf("--data-actions requires --role to be the name of the custom role to create")
+	}
+
+	for _, action := range dataActions {
+		if !strings.HasPrefix(action, cosmosDBDataActionPrefix) {
+			return "", fmt.Errorf("invalid data action '%s', Cosmos DB data actions start with '%s'", action, cosmosDBDataActionPrefix)
+		}
+	}
+
+	cmd := exec.Command(
+		"az", "cosmosdb", "sql", "role", "definition", "list",
+		"--account-name", cosmosDBName,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", fmt.Sprintf("[?roleName=='%s'].id | [0]", roleName),
+		"--output", "tsv",
+	)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to list CosmosDB role definitions: %w", clierror.New(err, stderr))
+	}
+	existingID := strings.TrimSpace(string(output))
+
+	// A fixed definition ID makes retrying the create idempotent
+	definitionID := existingID
+	if definitionID == "" {
+		if definitionID, err = newGUID(); err != nil {
+			return "", err
+		}
+	}
+
+	body, err := json.Marshal(cosmosDBRoleDefinitionBody(definitionID, roleName, dataActions))
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize CosmosDB role definition: %w", err)
+	}
+
+	operation := "create"
+	if existingID != "" {
+		operation = "update"
+		fmt.Printf("Updating custom role '%s' with %d data action(s)...\n", roleName, len(dataActions))
+	} else {
+		fmt.Printf("Creating custom role '%s' with %d data action(s)...\n", roleName, len(dataActions))
+	}
+
+	cmd = exec.Command(
+		"az", "cosmosdb", "sql", "role", "definition", operation,
+		"--account-name", cosmosDBName,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--body", string(body),
+		"--query", "id",
+		"--output", "tsv",
+	)
+
+	printCommand(cmd)
+
+	output, stderr, err = retry.Output(cmd)
 	if err != nil {
-		return "", fmt.Errorf("failed to get CosmosDB resource ID: %w\nOutput: %s", err, string(output))
+		return "", fmt.Errorf("failed to %s CosmosDB role definition '%s': %w", operation, roleName, clierror.New(err, stderr))
 	}
 
 	return strings.TrimSpace(string(output)), nil
 }
+
+func cosmosDBRoleDefinitionBody(id, roleName string, dataActions []string) map[string]interface{} {
+	return map[string]interface{}{
+		"Id":               id,
+		"RoleName":         roleName,
+		"Type":             "CustomRole",
+		"AssignableScopes": []string{"/"},
+		"Permissions": []map[string]interface{}{
+			{"DataActions": dataActions},
+		},
+	}
+}
+
+// newGUID returns a random GUID for resources whose ID is chosen by the client
+func newGUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate GUID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// cosmosDBRoleDefinitionID returns the ID of a built-in role, or expands the GUID of a role definition
+// on the account. Full role definition IDs are returned unchanged.
+func cosmosDBRoleDefinitionID(subscriptionID, resourceGroup, cosmosDBName, role string) (string, error) {
+	if strings.HasPrefix(role, "/") {
+		return role, nil
+	}
+
+	if guid, ok := cosmosDBBuiltInRoles[role]; ok {
+		role = guid
+	}
+
+	if !guidPattern.MatchString(role) {
+		return "", fmt.Errorf("unsupported role '%s', expected reader, contributor or a role definition ID", role)
+	}
+
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.DocumentDB/databaseAccounts/%s/sqlRoleDefinitions/%s",
+		subscriptionID, resourceGroup, cosmosDBName, role), nil
+}
+
+func cosmosDBScope(accountID, database, container string) string {
+	switch {
+	case container != "":
+		return fmt.Sprintf("%s/dbs/%s/colls/%s", accountID, database, container)
+	case database != "":
+		return fmt.Sprintf("%s/dbs/%s", accountID, database)
+	default:
+		return accountID
+	}
+}
+
+func (s *CosmosDBService) getCosmosDBEndpoint(name, resourceGroup string) (string, error) {
+	return s.run("failed to get CosmosDB endpoint",
+		"cosmosdb", "show",
+		"--name", name,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "documentEndpoint",
+		"--output", "tsv",
+	)
+}
+
+// cosmosDBProbePath returns the data-plane path of the account, database or container the role was assigned on
+func cosmosDBProbePath(database, container string) string {
+	switch {
+	case container != "":
+		return fmt.Sprintf("/dbs/%s/colls/%s", database, container)
+	case database != "":
+		return fmt.Sprintf("/dbs/%s", database)
+	default:
+		return "/dbs"
+	}
+}
+
+func (s *CosmosDBService) getResourceGroupLocation(resourceGroup string) (string, error) {
+	return s.run(fmt.Sprintf("failed to get location of resource group '%s'", resourceGroup),
+		"group", "show",
+		"--name", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "location",
+		"--output", "tsv",
+	)
+}
+
+// listCosmosDBRoleAssignments returns the SQL role assignments of the principal on the account
+func (s *service) listCosmosDBRoleAssignments(cosmosDBName, resourceGroup, principalID string) ([]cosmosDBRoleAssignment, error) {
+	var assignments []cosmosDBRoleAssignment
+	if err := s.runJSON(&assignments, fmt.Sprintf("failed to list role assignments of CosmosDB '%s'", cosmosDBName),
+		"cosmosdb", "sql", "role", "assignment", "list",
+		"--account-name", cosmosDBName,
+		"--resource-group", resourceGroup,
+	); err != nil {
+		return nil, err
+	}
+
+	var matching []cosmosDBRoleAssignment
+	for _, a := range assignments {
+		if strings.EqualFold(a.PrincipalID, principalID) {
+			matching = append(matching, a)
+		}
+	}
+
+	return matching, nil
+}
@@ -0,0 +1,41 @@
+package bind
+
+import "testing"
+
+func TestCosmosDBRoleDefinitionID(t *testing.T) {
+	id, err := cosmosDBRoleDefinitionID("sub", "rg", "cosmos", "reader")
+	if err != nil {
+		t.Fatalf("Failed to get role definition ID: %v", err)
+	}
+	expected := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.DocumentDB/databaseAccounts/cosmos/sqlRoleDefinitions/00000000-0000-0000-0000-000000000001"
+	if id != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, id)
+	}
+
+	custom := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.DocumentDB/databaseAccounts/cosmos/sqlRoleDefinitions/custom"
+	if id, _ := cosmosDBRoleDefinitionID("sub", "rg", "cosmos", custom); id != custom {
+		t.Errorf("Expected a full role definition ID to be returned unchanged, got '%s'", id)
+	}
+
+	if _, err := cosmosDBRoleDefinitionID("sub", "rg", "cosmos", "owner"); err == nil {
+		t.Error("Expected unsupported role to be rejected")
+	}
+}
+
+func TestCosmosDBScope(t *testing.T) {
+	tests := []struct {
+		database  string
+		container string
+		expected  string
+	}{
+		{"", "", "/accounts/cosmos"},
+		{"app", "", "/accounts/cosmos/dbs/app"},
+		{"app", "items", "/accounts/cosmos/dbs/app/colls/items"},
+	}
+
+	for _, test := range tests {
+		if scope := cosmosDBScope("/accounts/cosmos", test.database, test.container); scope != test.expected {
+			t.Errorf("Expected '%s', got '%s'", test.expected, scope)
+		}
+	}
+}
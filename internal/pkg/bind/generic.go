@@ -0,0 +1,121 @@
+package bind
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+var (
+	roleDefinitionIDPattern = regexp.MustCompile(`(?i)^(/subscriptions/[^/]+)?/providers/Microsoft\.Authorization/roleDefinitions/[0-9a-f-]{36}$`)
+	guidPattern             = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	scopePattern            = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)(/resourceGroups/([^/]+))?(/providers/.+)?$`)
+)
+
+type GenericService struct {
+	service
+}
+
+func NewGenericService(credential azcore.TokenCredential, subscriptionID string) *GenericService {
+	return &GenericService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// BindGeneric assigns any Azure RBAC role to the identity at any scope, for services that have no
+// dedicated binding. The role can be given by name, by GUID or by full role definition ID.
+func (s *GenericService) BindGeneric(ctx context.Context, scope, role, identityName, identityResourceGroup string) error {
+	if err := s.validateScope(scope); err != nil {
+		return err
+	}
+
+	roleDefinitionID, err := s.resolveRoleDefinitionID(role, scope)
+	if err != nil {
+		return err
+	}
+
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Assigning role '%s' at scope '%s'...\n", role, scope)
+	if err := s.assignRole(identityPrincipalID, roleDefinitionID, scope); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	if err := s.waitForRoleAssignment(ctx, identityPrincipalID, roleDefinitionID, scope); err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully assigned role '%s' to identity '%s'\n", role, identityName)
+
+	return nil
+}
+
+// validateScope checks that the subscription, resource group or resource the scope points to exists
+func (s *GenericService) validateScope(scope string) error {
+	match := scopePattern.FindStringSubmatch(scope)
+	if match == nil {
+		return fmt.Errorf("invalid scope '%s', expected a subscription, resource group or resource ID starting with /subscriptions/", scope)
+	}
+
+	var cmd *exec.Cmd
+	switch {
+	case match[4] != "":
+		cmd = exec.Command("az", "resource", "show", "--ids", scope, "--query", "id", "--output", "tsv")
+	case match[3] != "":
+		cmd = exec.Command("az", "group", "show", "--name", match[3], "--subscription", match[1], "--query", "id", "--output", "tsv")
+	default:
+		cmd = exec.Command("az", "account", "show", "--subscription", match[1], "--query", "id", "--output", "tsv")
+	}
+
+	printCommand(cmd)
+
+	_, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return fmt.Errorf("scope '%s' not found: %w", scope, clierror.New(err, stderr))
+	}
+
+	return nil
+}
+
+// resolveRoleDefinitionID returns the ID of the role definition with the given name, GUID or ID
+// that is assignable at the scope
+func (s *GenericService) resolveRoleDefinitionID(role, scope string) (string, error) {
+	if roleDefinitionIDPattern.MatchString(role) {
+		return role, nil
+	}
+
+	// --name matches both the role name and the GUID of the role definition
+	cmd := exec.Command(
+		"az", "role", "definition", "list",
+		"--name", role,
+		"--scope", scope,
+		"--query", "[0].id",
+		"--output", "tsv",
+	)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up role '%s': %w", role, clierror.New(err, stderr))
+	}
+
+	roleDefinitionID := strings.TrimSpace(string(output))
+	if roleDefinitionID == "" {
+		return "", fmt.Errorf("role '%s' not found, use the name, GUID or ID of a role definition assignable at scope '%s'", role, scope)
+	}
+
+	return roleDefinitionID, nil
+}
@@ -0,0 +1,41 @@
+package bind
+
+import "testing"
+
+func TestScopePattern(t *testing.T) {
+	tests := map[string][]string{
+		"/subscriptions/sub":                   {"sub", "", ""},
+		"/subscriptions/sub/resourceGroups/rg": {"sub", "rg", ""},
+		"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Web/sites/app": {"sub", "rg", "/providers/Microsoft.Web/sites/app"},
+	}
+
+	for scope, expected := range tests {
+		match := scopePattern.FindStringSubmatch(scope)
+		if match == nil {
+			t.Fatalf("Expected scope '%s' to be valid", scope)
+		}
+		if match[1] != expected[0] || match[3] != expected[1] || match[4] != expected[2] {
+			t.Errorf("Unexpected parts for scope '%s': %q", scope, match)
+		}
+	}
+
+	if scopePattern.MatchString("my-resource-group") {
+		t.Error("Expected a bare name to be rejected as a scope")
+	}
+}
+
+func TestRoleDefinitionIDPattern(t *testing.T) {
+	ids := []string{
+		"/providers/Microsoft.Authorization/roleDefinitions/7f951dda-4ed3-4680-a7ca-43fe172d538d",
+		"/subscriptions/sub/providers/Microsoft.Authorization/roleDefinitions/7f951dda-4ed3-4680-a7ca-43fe172d538d",
+	}
+	for _, id := range ids {
+		if !roleDefinitionIDPattern.MatchString(id) {
+			t.Errorf("Expected '%s' to be a role definition ID", id)
+		}
+	}
+
+	if roleDefinitionIDPattern.MatchString("AcrPull") {
+		t.Error("Expected a role name not to be a role definition ID")
+	}
+}
@@ -0,0 +1,163 @@
+package bind
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// DefaultKeyVaultRole is the role assigned when no role is specified
+const DefaultKeyVaultRole = "Key Vault Secrets User"
+
+// keyVaultAccessPolicies maps the built-in Key Vault data roles to the equivalent
+// access policy permissions, for vaults that do not use Azure RBAC
+var keyVaultAccessPolicies = map[string]keyVaultPermissions{
+	"Key Vault Secrets User": {
+		secrets: []string{"get", "list"},
+	},
+	"Key Vault Secrets Officer": {
+		secrets: []string{"get", "list", "set", "delete", "backup", "restore", "recover"},
+	},
+	"Key Vault Crypto User": {
+		keys: []string{"get", "list", "encrypt", "decrypt", "wrapKey", "unwrapKey", "sign", "verify"},
+	},
+	"Key Vault Crypto Officer": {
+		keys: []string{"get", "list", "create", "update", "import", "delete", "backup", "restore", "recover", "encrypt", "decrypt", "wrapKey", "unwrapKey", "sign", "verify", "rotate"},
+	},
+	"Key Vault Reader": {
+		secrets: []string{"list"},
+		keys:    []string{"get", "list"},
+	},
+}
+
+type keyVaultPermissions struct {
+	secrets []string
+	keys    []string
+}
+
+type KeyVaultService struct {
+	service
+}
+
+type keyVault struct {
+	ID                      string `json:"id"`
+	VaultURI                string `json:"vaultUri"`
+	EnableRbacAuthorization bool   `json:"enableRbacAuthorization"`
+}
+
+func NewKeyVaultService(credential azcore.TokenCredential, subscriptionID string) *KeyVaultService {
+	return &KeyVaultService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// BindKeyVault grants the identity access to the vault's data. Vaults using Azure RBAC get a role
+// assignment, legacy vaults using access policies get an access policy with equivalent permissions.
+func (s *KeyVaultService) BindKeyVault(ctx context.Context, name, resourceGroup, role, identityName, identityResourceGroup string) error {
+	vault, err := s.getKeyVault(name, resourceGroup)
+	if err != nil {
+		return err
+	}
+
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return err
+	}
+
+	if vault.EnableRbacAuthorization {
+		fmt.Printf("Key Vault '%s' uses Azure RBAC, assigning role '%s'...\n", name, role)
+		if err := s.assignRole(identityPrincipalID, role, vault.ID); err != nil {
+			return fmt.Errorf("failed to assign role to Key Vault: %w", err)
+		}
+	} else {
+		fmt.Printf("Key Vault '%s' uses access policies, adding an access policy equivalent to '%s'...\n", name, role)
+		if err := s.setAccessPolicy(identityPrincipalID, name, resourceGroup, role); err != nil {
+			return err
+		}
+	}
+
+	// Crypto roles grant no access to secrets, so check access to keys instead
+	collection := "secrets"
+	if permissions, ok := keyVaultAccessPolicies[role]; ok && len(permissions.secrets) == 0 {
+		collection = "keys"
+	}
+
+	if err := s.waitForDataPlane(ctx, identityName, dataPlaneProbe{
+		audience: "https://vault.azure.net",
+		url:      fmt.Sprintf("%s%s?api-version=7.4", vault.VaultURI, collection),
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully bound Key Vault '%s' to identity '%s'\n", name, identityName)
+
+	return nil
+}
+
+func (s *KeyVaultService) getKeyVault(name, resourceGroup string) (*keyVault, error) {
+	cmd := exec.Command(
+		"az", "keyvault", "show",
+		"--name", name,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "{id:id, vaultUri:properties.vaultUri, enableRbacAuthorization:properties.enableRbacAuthorization}",
+		"--output", "json",
+	)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("Key Vault '%s' not found in resource group '%s': %w",
+			name, resourceGroup, clierror.New(err, stderr))
+	}
+
+	var vault keyVault
+	if err := json.Unmarshal(output, &vault); err != nil {
+		return nil, fmt.Errorf("failed to parse Key Vault '%s': %w", name, err)
+	}
+
+	return &vault, nil
+}
+
+func (s *KeyVaultService) setAccessPolicy(identityPrincipalID, name, resourceGroup, role string) error {
+	permissions, ok := keyVaultAccessPolicies[role]
+	if !ok {
+		return fmt.Errorf("Key Vault '%s' uses access policies and role '%s' has no access policy equivalent, use one of the built-in Key Vault roles or enable Azure RBAC on the vault", name, role)
+	}
+
+	args := []string{
+		"keyvault", "set-policy",
+		"--name", name,
+		"--resource-group", resourceGroup,
+		"--object-id", identityPrincipalID,
+		"--subscription", s.subscriptionID,
+	}
+	if len(permissions.secrets) > 0 {
+		args = append(args, "--secret-permissions")
+		args = append(args, permissions.secrets...)
+	}
+	if len(permissions.keys) > 0 {
+		args = append(args, "--key-permissions")
+		args = append(args, permissions.keys...)
+	}
+
+	cmd := exec.Command("az", args...)
+
+	printCommand(cmd)
+
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to set Key Vault access policy: %w", clierror.New(err, output))
+	}
+
+	return nil
+}
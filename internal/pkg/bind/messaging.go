@@ -0,0 +1,161 @@
+package bind
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// messagingRoles maps an access level to the suffix of the built-in Service Bus and Event Hubs data roles
+var messagingRoles = map[string]string{
+	"sender":   "Data Sender",
+	"receiver": "Data Receiver",
+	"owner":    "Data Owner",
+}
+
+// messagingRole returns the built-in role of the messaging service granting the access level,
+// e.g. "Azure Service Bus Data Sender"
+func messagingRole(serviceName, access string) (string, error) {
+	suffix, ok := messagingRoles[access]
+	if !ok {
+		return "", fmt.Errorf("unsupported role '%s', expected sender, receiver or owner", access)
+	}
+
+	return fmt.Sprintf("Azure %s %s", serviceName, suffix), nil
+}
+
+// messagingProbeURL returns the data-plane URL of the queue, topic or event hub, or of the namespace
+// when entity is empty
+func messagingProbeURL(namespace, entity, apiVersion string) string {
+	return fmt.Sprintf("https://%s.servicebus.windows.net/%s?api-version=%s", namespace, url.PathEscape(entity), apiVersion)
+}
+
+// waitForMessaging waits for a messaging data role to take effect. Reading the entity description needs the
+// Manage claim, which only the Data Owner role grants, and sending or receiving messages to check the other
+// roles would change the entity's messages, so for those it only waits until the role assignment is visible.
+func (s *service) waitForMessaging(ctx context.Context, access, identityName, identityPrincipalID, role, scope string, probe dataPlaneProbe) error {
+	if access != "owner" {
+		return s.waitForRoleAssignment(ctx, identityPrincipalID, role, scope)
+	}
+
+	return s.waitForDataPlane(ctx, identityName, probe)
+}
+
+type ServiceBusService struct {
+	service
+}
+
+func NewServiceBusService(credential azcore.TokenCredential, subscriptionID string) *ServiceBusService {
+	return &ServiceBusService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// BindServiceBus grants the identity a Service Bus data role on a namespace, or on a single queue or topic
+func (s *ServiceBusService) BindServiceBus(ctx context.Context, namespace, resourceGroup, queue, topic, access, identityName, identityResourceGroup string) error {
+	if queue != "" && topic != "" {
+		return fmt.Errorf("only one of queue and topic can be set")
+	}
+
+	role, err := messagingRole("Service Bus", access)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"servicebus", "namespace", "show", "--name", namespace}
+	entity := fmt.Sprintf("Service Bus namespace '%s'", namespace)
+	switch {
+	case queue != "":
+		args = []string{"servicebus", "queue", "show", "--namespace-name", namespace, "--name", queue}
+		entity = fmt.Sprintf("Service Bus queue '%s' in namespace '%s'", queue, namespace)
+	case topic != "":
+		args = []string{"servicebus", "topic", "show", "--namespace-name", namespace, "--name", topic}
+		entity = fmt.Sprintf("Service Bus topic '%s' in namespace '%s'", topic, namespace)
+	}
+
+	scope, err := s.getResourceID(entity, resourceGroup, args...)
+	if err != nil {
+		return err
+	}
+
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Assigning role '%s' on %s...\n", role, entity)
+	if err := s.assignRole(identityPrincipalID, role, scope); err != nil {
+		return fmt.Errorf("failed to assign role to Service Bus: %w", err)
+	}
+
+	// A missing entity returns not found once the token is accepted, so the namespace itself can be probed
+	if err := s.waitForMessaging(ctx, access, identityName, identityPrincipalID, role, scope, dataPlaneProbe{
+		audience: "https://servicebus.azure.net",
+		url:      messagingProbeURL(namespace, queue+topic, "2017-04"),
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully bound %s to identity '%s'\n", entity, identityName)
+
+	return nil
+}
+
+type EventHubsService struct {
+	service
+}
+
+func NewEventHubsService(credential azcore.TokenCredential, subscriptionID string) *EventHubsService {
+	return &EventHubsService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// BindEventHubs grants the identity an Event Hubs data role on a namespace, or on a single event hub
+func (s *EventHubsService) BindEventHubs(ctx context.Context, namespace, resourceGroup, eventHub, access, identityName, identityResourceGroup string) error {
+	role, err := messagingRole("Event Hubs", access)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"eventhubs", "namespace", "show", "--name", namespace}
+	entity := fmt.Sprintf("Event Hubs namespace '%s'", namespace)
+	if eventHub != "" {
+		args = []string{"eventhubs", "eventhub", "show", "--namespace-name", namespace, "--name", eventHub}
+		entity = fmt.Sprintf("event hub '%s' in namespace '%s'", eventHub, namespace)
+	}
+
+	scope, err := s.getResourceID(entity, resourceGroup, args...)
+	if err != nil {
+		return err
+	}
+
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Assigning role '%s' on %s...\n", role, entity)
+	if err := s.assignRole(identityPrincipalID, role, scope); err != nil {
+		return fmt.Errorf("failed to assign role to Event Hubs: %w", err)
+	}
+
+	if err := s.waitForMessaging(ctx, access, identityName, identityPrincipalID, role, scope, dataPlaneProbe{
+		audience: "https://eventhubs.azure.net",
+		url:      messagingProbeURL(namespace, eventHub, "2014-01"),
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully bound %s to identity '%s'\n", entity, identityName)
+
+	return nil
+}
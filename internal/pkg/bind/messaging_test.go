@@ -0,0 +1,35 @@
+package bind
+
+import "testing"
+
+func TestMessagingRole(t *testing.T) {
+	role, err := messagingRole("Service Bus", "sender")
+	if err != nil {
+		t.Fatalf("Failed to get role: %v", err)
+	}
+	if role != "Azure Service Bus Data Sender" {
+		t.Errorf("Expected 'Azure Service Bus Data Sender', got '%s'", role)
+	}
+
+	role, err = messagingRole("Event Hubs", "owner")
+	if err != nil {
+		t.Fatalf("Failed to get role: %v", err)
+	}
+	if role != "Azure Event Hubs Data Owner" {
+		t.Errorf("Expected 'Azure Event Hubs Data Owner', got '%s'", role)
+	}
+
+	if _, err := messagingRole("Service Bus", "listen"); err == nil {
+		t.Error("Expected unsupported role to be rejected")
+	}
+}
+
+func TestMessagingProbeURL(t *testing.T) {
+	if got := messagingProbeURL("my-servicebus", "orders", "2017-04"); got != "https://my-servicebus.servicebus.windows.net/orders?api-version=2017-04" {
+		t.Errorf("Unexpected queue probe URL: %s", got)
+	}
+
+	if got := messagingProbeURL("my-eventhubs", "", "2014-01"); got != "https://my-eventhubs.servicebus.windows.net/?api-version=2014-01" {
+		t.Errorf("Unexpected namespace probe URL: %s", got)
+	}
+}
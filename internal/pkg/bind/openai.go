@@ -0,0 +1,141 @@
+package bind
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// DefaultOpenAIRole is the role assigned when no role is specified
+const DefaultOpenAIRole = "Cognitive Services OpenAI User"
+
+type OpenAIService struct {
+	service
+}
+
+type cognitiveServicesAccount struct {
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	Endpoint string `json:"endpoint"`
+}
+
+// OpenAIDeployment is a model deployment on an Azure OpenAI or AI Services account
+type OpenAIDeployment struct {
+	Name         string `json:"name"`
+	Model        string `json:"model"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+// OpenAIAccount describes the endpoint and model deployments available to the identity
+type OpenAIAccount struct {
+	Endpoint    string
+	Deployments []OpenAIDeployment
+}
+
+func NewOpenAIService(credential azcore.TokenCredential, subscriptionID string) *OpenAIService {
+	return &OpenAIService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// BindOpenAI grants the identity a role on an Azure OpenAI or AI Services account and returns
+// the account's endpoint and model deployments
+func (s *OpenAIService) BindOpenAI(ctx context.Context, name, resourceGroup, role, identityName, identityResourceGroup string) (*OpenAIAccount, error) {
+	account, err := s.getAccount(name, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.Kind != "OpenAI" && account.Kind != "AIServices" {
+		return nil, fmt.Errorf("account '%s' is of kind '%s', expected an Azure OpenAI or AI Services account", name, account.Kind)
+	}
+
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Assigning role '%s' on account '%s'...\n", role, name)
+	if err := s.assignRole(identityPrincipalID, role, account.ID); err != nil {
+		return nil, fmt.Errorf("failed to assign role to Azure OpenAI account: %w", err)
+	}
+
+	if err := s.waitForDataPlane(ctx, identityName, dataPlaneProbe{
+		audience: "https://cognitiveservices.azure.com",
+		url:      strings.TrimSuffix(account.Endpoint, "/") + "/openai/models?api-version=2024-10-21",
+	}); err != nil {
+		return nil, err
+	}
+
+	deployments, err := s.listDeployments(name, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Successfully bound Azure OpenAI account '%s' to identity '%s'\n", name, identityName)
+
+	return &OpenAIAccount{
+		Endpoint:    account.Endpoint,
+		Deployments: deployments,
+	}, nil
+}
+
+func (s *OpenAIService) getAccount(name, resourceGroup string) (*cognitiveServicesAccount, error) {
+	cmd := exec.Command(
+		"az", "cognitiveservices", "account", "show",
+		"--name", name,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "{id:id, kind:kind, endpoint:properties.endpoint}",
+		"--output", "json",
+	)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("Azure OpenAI account '%s' not found in resource group '%s': %w",
+			name, resourceGroup, clierror.New(err, stderr))
+	}
+
+	var account cognitiveServicesAccount
+	if err := json.Unmarshal(output, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse Azure OpenAI account '%s': %w", name, err)
+	}
+
+	return &account, nil
+}
+
+func (s *OpenAIService) listDeployments(name, resourceGroup string) ([]OpenAIDeployment, error) {
+	cmd := exec.Command(
+		"az", "cognitiveservices", "account", "deployment", "list",
+		"--name", name,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "[].{name:name, model:properties.model.name, modelVersion:properties.model.version}",
+		"--output", "json",
+	)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model deployments: %w", clierror.New(err, stderr))
+	}
+
+	var deployments []OpenAIDeployment
+	if err := json.Unmarshal(output, &deployments); err != nil {
+		return nil, fmt.Errorf("failed to parse model deployments: %w", err)
+	}
+
+	return deployments, nil
+}
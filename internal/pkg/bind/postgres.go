@@ -0,0 +1,229 @@
+package bind
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+type PostgresService struct {
+	service
+}
+
+type postgresServer struct {
+	FullyQualifiedDomainName string `json:"fullyQualifiedDomainName"`
+	ActiveDirectoryAuth      string `json:"activeDirectoryAuth"`
+}
+
+// PostgresConnection holds what a Spin app needs to connect to the server with Entra authentication
+type PostgresConnection struct {
+	Host     string
+	Port     int
+	Database string
+	User     string
+	SSLMode  string
+}
+
+func NewPostgresService(credential azcore.TokenCredential, subscriptionID string) *PostgresService {
+	return &PostgresService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// BindPostgres adds the identity to an Azure Database for PostgreSQL Flexible Server, either as a
+// Microsoft Entra administrator or as a database role created by the signed-in Entra administrator
+func (s *PostgresService) BindPostgres(ctx context.Context, serverName, resourceGroup, database string, admin bool, identityName, identityResourceGroup string) (*PostgresConnection, error) {
+	server, err := s.getPostgresServer(serverName, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if server.ActiveDirectoryAuth != "Enabled" {
+		return nil, fmt.Errorf("Microsoft Entra authentication is not enabled on PostgreSQL server '%s', enable it using 'az postgres flexible-server update --name %s --resource-group %s --microsoft-entra-auth Enabled'", serverName, serverName, resourceGroup)
+	}
+
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if admin {
+		if err := s.addEntraAdmin(serverName, resourceGroup, identityName, identityPrincipalID); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := s.createDatabaseRole(server.FullyQualifiedDomainName, database, identityName, identityPrincipalID); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.waitTimeout > 0 {
+		fmt.Println("Access to PostgreSQL takes effect immediately, nothing to wait for")
+	}
+
+	fmt.Printf("Successfully bound PostgreSQL server '%s' to identity '%s'\n", serverName, identityName)
+
+	return &PostgresConnection{
+		Host:     server.FullyQualifiedDomainName,
+		Port:     5432,
+		Database: database,
+		User:     identityName,
+		SSLMode:  "require",
+	}, nil
+}
+
+func (s *PostgresService) getPostgresServer(name, resourceGroup string) (*postgresServer, error) {
+	cmd := exec.Command(
+		"az", "postgres", "flexible-server", "show",
+		"--name", name,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "{fullyQualifiedDomainName:fullyQualifiedDomainName, activeDirectoryAuth:authConfig.activeDirectoryAuth}",
+		"--output", "json",
+	)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("PostgreSQL server '%s' not found in resource group '%s': %w",
+			name, resourceGroup, clierror.New(err, stderr))
+	}
+
+	var server postgresServer
+	if err := json.Unmarshal(output, &server); err != nil {
+		return nil, fmt.Errorf("failed to parse PostgreSQL server '%s': %w", name, err)
+	}
+
+	return &server, nil
+}
+
+func (s *PostgresService) addEntraAdmin(serverName, resourceGroup, identityName, identityPrincipalID string) error {
+	cmd := exec.Command(
+		"az", "postgres", "flexible-server", "ad-admin", "create",
+		"--server-name", serverName,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--display-name", identityName,
+		"--object-id", identityPrincipalID,
+		"--type", "ServicePrincipal",
+	)
+
+	printCommand(cmd)
+
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to add identity as Microsoft Entra administrator: %w", clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// createDatabaseRoleScript creates the role mapped to the identity unless it already exists, and lets it
+// connect to the database. psql interpolates the variables in scripts read from stdin, but not in --command.
+const createDatabaseRoleScript = `select exists (select 1 from pg_roles where rolname = :'role_name') as role_exists \gset
+\if :role_exists
+\echo Role :"role_name" already exists
+\else
+select * from pgaadauth_create_principal_with_oid(:'role_name', :'object_id', 'service', false, false);
+\endif
+grant connect on database :"database" to :"role_name";
+`
+
+// dropDatabaseRoleScript removes the role mapped to the identity if it exists, along with its access to the
+// database, and prints how many roles were dropped
+const dropDatabaseRoleScript = `select count(*) as role_count from pg_roles where rolname = :'role_name' \gset
+\if :role_count
+revoke all privileges on database :"database" from :"role_name";
+drop role :"role_name";
+\endif
+\echo :role_count
+`
+
+// createDatabaseRole creates a role mapped to the identity unless it already exists
+func (s *PostgresService) createDatabaseRole(host, database, identityName, identityPrincipalID string) error {
+	_, err := s.runAsEntraAdmin(host, fmt.Sprintf("Creating PostgreSQL role '%s'", identityName), createDatabaseRoleScript, map[string]string{
+		"role_name": identityName,
+		"object_id": identityPrincipalID,
+		"database":  database,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create PostgreSQL role: %w", err)
+	}
+
+	return nil
+}
+
+// dropDatabaseRole drops the role mapped to the identity and returns the number of roles dropped
+func (s *PostgresService) dropDatabaseRole(host, database, identityName string) (int, error) {
+	output, err := s.runAsEntraAdmin(host, fmt.Sprintf("Dropping PostgreSQL role '%s'", identityName), dropDatabaseRoleScript, map[string]string{
+		"role_name": identityName,
+		"database":  database,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to drop PostgreSQL role: %w", err)
+	}
+
+	lines := strings.Split(output, "\n")
+	count, err := strconv.Atoi(strings.TrimSpace(lines[len(lines)-1]))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected psql output: %s", output)
+	}
+
+	return count, nil
+}
+
+// runAsEntraAdmin connects to the server as the signed-in user, who must be a Microsoft Entra administrator
+// of the server, runs the psql script and returns its output. The variables are passed with --set, and the
+// script quotes them as literals and identifiers.
+func (s *PostgresService) runAsEntraAdmin(host, action, script string, variables map[string]string) (string, error) {
+	userCmd := exec.Command("az", "ad", "signed-in-user", "show", "--query", "userPrincipalName", "--output", "tsv")
+	output, stderr, err := retry.Output(userCmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to get signed-in user: %w", clierror.New(err, stderr))
+	}
+	adminUser := strings.TrimSpace(string(output))
+
+	tokenCmd := exec.Command("az", "account", "get-access-token", "--resource-type", "oss-rdbms", "--query", "accessToken", "--output", "tsv")
+	output, stderr, err = retry.Output(tokenCmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token for PostgreSQL: %w", clierror.New(err, stderr))
+	}
+	token := strings.TrimSpace(string(output))
+
+	args := []string{
+		fmt.Sprintf("host=%s port=5432 dbname=postgres user=%s sslmode=require", host, adminUser),
+		"--no-psqlrc",
+		"--quiet",
+		"--tuples-only",
+		"--no-align",
+		"--set", "ON_ERROR_STOP=1",
+	}
+	for _, name := range sortedKeys(variables) {
+		args = append(args, "--set", name+"="+variables[name])
+	}
+
+	cmd := exec.Command("psql", args...)
+	cmd.Env = append(os.Environ(), "PGPASSWORD="+token)
+	cmd.Stdin = strings.NewReader(script)
+
+	fmt.Printf("%s as '%s'...\n", action, adminUser)
+
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("psql failed, make sure psql is installed and '%s' is a Microsoft Entra administrator of the server: %w", adminUser, clierror.New(err, output))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,213 @@
+package bind
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// redisAccessPolicies maps an access level to the built-in Azure Cache for Redis data access policy
+var redisAccessPolicies = map[string]string{
+	"owner":       "Data Owner",
+	"contributor": "Data Contributor",
+	"reader":      "Data Reader",
+}
+
+type RedisService struct {
+	service
+}
+
+type redisCache struct {
+	HostName   string `json:"hostName"`
+	SSLPort    int    `json:"sslPort"`
+	AADEnabled string `json:"aadEnabled"`
+}
+
+// RedisConnection holds what a Spin app needs to connect to the cache with Entra authentication
+type RedisConnection struct {
+	HostName string
+	Port     int
+	Username string
+}
+
+func NewRedisService(credential azcore.TokenCredential, subscriptionID string) *RedisService {
+	return &RedisService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// BindRedis enables Microsoft Entra authentication on the cache if needed and creates a data access
+// policy assignment for the identity. Azure Cache for Redis does not use Azure RBAC for data access.
+func (s *RedisService) BindRedis(ctx context.Context, name, resourceGroup, access, identityName, identityResourceGroup string) (*RedisConnection, error) {
+	policy, ok := redisAccessPolicies[access]
+	if !ok {
+		return nil, fmt.Errorf("unsupported role '%s', expected owner, contributor or reader", access)
+	}
+
+	cache, err := s.getRedisCache(name, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(cache.AADEnabled, "true") {
+		fmt.Printf("Microsoft Entra authentication is not enabled on Redis cache '%s', enabling it now...\n", name)
+		if err := s.enableEntraAuthentication(name, resourceGroup); err != nil {
+			return nil, err
+		}
+	}
+
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.listRedisAccessPolicyAssignments(name, resourceGroup, identityPrincipalID)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := false
+	for _, a := range existing {
+		assigned = assigned || a.AccessPolicyName == policy
+	}
+
+	if assigned {
+		fmt.Printf("Access policy '%s' is already assigned to identity '%s'\n", policy, identityName)
+	} else if err := s.createAccessPolicyAssignment(name, resourceGroup, policy, identityName, identityPrincipalID); err != nil {
+		return nil, err
+	}
+
+	if err := s.waitForAccessPolicyAssignment(ctx, name, resourceGroup, policy, identityPrincipalID); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Successfully bound Redis cache '%s' to identity '%s'\n", name, identityName)
+
+	return &RedisConnection{
+		HostName: cache.HostName,
+		Port:     cache.SSLPort,
+		Username: identityPrincipalID,
+	}, nil
+}
+
+func (s *RedisService) createAccessPolicyAssignment(name, resourceGroup, policy, identityName, identityPrincipalID string) error {
+	cmd := exec.Command(
+		"az", "redis", "access-policy-assignment", "create",
+		"--name", identityName,
+		"--policy-name", policy,
+		"--object-id", identityPrincipalID,
+		"--object-id-alias", identityName,
+		"--redis-cache-name", name,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+	)
+
+	printCommand(cmd)
+
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to create Redis access policy assignment: %w", clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// waitForAccessPolicyAssignment waits until the access policy assignment has been provisioned on the cache
+func (s *RedisService) waitForAccessPolicyAssignment(ctx context.Context, name, resourceGroup, policy, identityPrincipalID string) error {
+	if s.waitTimeout == 0 {
+		return nil
+	}
+
+	fmt.Printf("Waiting up to %s for access policy '%s' to be provisioned...\n", s.waitTimeout, policy)
+
+	return poll(ctx, s.waitTimeout, func() (bool, error) {
+		assignments, err := s.listRedisAccessPolicyAssignments(name, resourceGroup, identityPrincipalID)
+		if err != nil {
+			return false, err
+		}
+
+		for _, a := range assignments {
+			if a.AccessPolicyName == policy && a.ProvisioningState == "Succeeded" {
+				fmt.Println("Access is effective")
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+func (s *RedisService) getRedisCache(name, resourceGroup string) (*redisCache, error) {
+	cmd := exec.Command(
+		"az", "redis", "show",
+		"--name", name,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", `{hostName:hostName, sslPort:sslPort, aadEnabled:redisConfiguration."aad-enabled"}`,
+		"--output", "json",
+	)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("Redis cache '%s' not found in resource group '%s': %w",
+			name, resourceGroup, clierror.New(err, stderr))
+	}
+
+	var cache redisCache
+	if err := json.Unmarshal(output, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse Redis cache '%s': %w", name, err)
+	}
+
+	return &cache, nil
+}
+
+func (s *RedisService) enableEntraAuthentication(name, resourceGroup string) error {
+	cmd := exec.Command(
+		"az", "redis", "update",
+		"--name", name,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--set", "redisConfiguration.aad-enabled=true",
+	)
+
+	printCommand(cmd)
+
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to enable Microsoft Entra authentication on Redis cache '%s': %w", name, clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// listRedisAccessPolicyAssignments returns the access policy assignments of the object on the cache
+func (s *service) listRedisAccessPolicyAssignments(name, resourceGroup, objectID string) ([]redisAccessPolicyAssignment, error) {
+	var assignments []redisAccessPolicyAssignment
+	if err := s.runJSON(&assignments, fmt.Sprintf("failed to list access policy assignments of Redis cache '%s'", name),
+		"redis", "access-policy-assignment", "list",
+		"--redis-cache-name", name,
+		"--resource-group", resourceGroup,
+	); err != nil {
+		return nil, err
+	}
+
+	var matching []redisAccessPolicyAssignment
+	for _, a := range assignments {
+		if strings.EqualFold(a.ObjectID, objectID) {
+			matching = append(matching, a)
+		}
+	}
+
+	return matching, nil
+}
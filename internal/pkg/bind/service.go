@@ -0,0 +1,149 @@
+package bind
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// service holds what every binding needs to talk to Azure
+type service struct {
+	credential     azcore.TokenCredential
+	subscriptionID string
+	// waitTimeout is how long to wait for access to take effect after binding, zero disables waiting
+	waitTimeout time.Duration
+	// namespace is the Kubernetes namespace of the identity's service account
+	namespace string
+	// probeImage is the image of the pod checking data-plane access, the default is used when empty
+	probeImage string
+}
+
+func (s *service) getIdentityPrincipalID(name, resourceGroup string) (string, error) {
+	cmd := exec.Command(
+		"az", "identity", "show",
+		"--name", name,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "principalId",
+		"--output", "tsv",
+	)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to get identity principal ID: %w", clierror.New(err, stderr))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// assignRole assigns an Azure RBAC role to the identity's service principal at the given scope,
+// unless the role is already assigned there
+func (s *service) assignRole(identityPrincipalID, role, scope string) error {
+	existing, err := s.run("failed to list role assignments",
+		"role", "assignment", "list",
+		"--assignee", identityPrincipalID,
+		"--role", role,
+		"--scope", scope,
+		"--subscription", s.subscriptionID,
+		"--query", "[].id",
+		"--output", "tsv",
+	)
+	if err != nil {
+		return err
+	}
+
+	if existing != "" {
+		fmt.Printf("Role '%s' is already assigned at scope '%s'\n", role, scope)
+		return nil
+	}
+
+	cmd := exec.Command(
+		"az", "role", "assignment", "create",
+		"--assignee-object-id", identityPrincipalID,
+		"--assignee-principal-type", "ServicePrincipal",
+		"--role", role,
+		"--scope", scope,
+		"--subscription", s.subscriptionID,
+	)
+
+	printCommand(cmd)
+
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		// A retried create finds the assignment made by the attempt that reported a transient failure
+		if strings.Contains(string(output), "RoleAssignmentExists") {
+			fmt.Printf("Role '%s' is already assigned at scope '%s'\n", role, scope)
+			return nil
+		}
+		return fmt.Errorf("failed to assign role '%s': %w", role, clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// getResourceID runs an az show command and returns the ID of the resource, which also
+// validates that the resource exists
+func (s *service) getResourceID(description, resourceGroup string, args ...string) (string, error) {
+	args = append(args,
+		"--resource-group", resourceGroup,
+		"--subscription", s.subscriptionID,
+		"--query", "id",
+		"--output", "tsv",
+	)
+	cmd := exec.Command("az", args...)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%s not found in resource group '%s': %w",
+			description, resourceGroup, clierror.New(err, stderr))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// run runs an az command against the subscription and returns its trimmed standard output,
+// leaving out warnings az writes to standard error
+func (s *service) run(description string, args ...string) (string, error) {
+	cmd := exec.Command("az", args...)
+
+	printCommand(cmd)
+
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", description, clierror.New(err, stderr))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// runJSON runs an az command with JSON output and decodes the output into v
+func (s *service) runJSON(v interface{}, description string, args ...string) error {
+	args = append(args, "--subscription", s.subscriptionID, "--output", "json")
+	output, err := s.run(description, args...)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(output), v); err != nil {
+		return fmt.Errorf("%s: %w", description, err)
+	}
+
+	return nil
+}
+
+// printCommand prints the command about to run to standard error, which keeps standard output
+// parseable for commands with JSON output
+func printCommand(cmd *exec.Cmd) {
+	fmt.Fprintln(os.Stderr, "Executing command:", strings.Join(cmd.Args, " "))
+}
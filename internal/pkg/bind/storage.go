@@ -0,0 +1,127 @@
+package bind
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// storageRoles maps a storage service and access level to the built-in data-plane role
+var storageRoles = map[string]map[string]string{
+	"blob": {
+		"read":  "Storage Blob Data Reader",
+		"write": "Storage Blob Data Contributor",
+	},
+	"queue": {
+		"read":  "Storage Queue Data Reader",
+		"write": "Storage Queue Data Contributor",
+	},
+	"table": {
+		"read":  "Storage Table Data Reader",
+		"write": "Storage Table Data Contributor",
+	},
+}
+
+// storageContainerScopes maps a storage service to the path of its containers below the account
+var storageContainerScopes = map[string]string{
+	"blob":  "blobServices/default/containers",
+	"queue": "queueServices/default/queues",
+	"table": "tableServices/default/tables",
+}
+
+type StorageService struct {
+	service
+}
+
+func NewStorageService(credential azcore.TokenCredential, subscriptionID string) *StorageService {
+	return &StorageService{
+		service: service{
+			credential:     credential,
+			subscriptionID: subscriptionID,
+		},
+	}
+}
+
+// BindStorage grants the identity read or write access to the blobs, queues or tables of a storage
+// account. When container is set, the role is scoped to that blob container, queue or table.
+func (s *StorageService) BindStorage(ctx context.Context, account, resourceGroup, storageService, container, access, identityName, identityResourceGroup string) error {
+	role, err := storageRole(storageService, access)
+	if err != nil {
+		return err
+	}
+
+	accountID, err := s.getResourceID(fmt.Sprintf("storage account '%s'", account), resourceGroup,
+		"storage", "account", "show", "--name", account)
+	if err != nil {
+		return err
+	}
+
+	identityPrincipalID, err := s.getIdentityPrincipalID(identityName, identityResourceGroup)
+	if err != nil {
+		return err
+	}
+
+	scope := storageScope(accountID, storageService, container)
+	fmt.Printf("Assigning role '%s' at scope '%s'...\n", role, scope)
+	if err := s.assignRole(identityPrincipalID, role, scope); err != nil {
+		return fmt.Errorf("failed to assign role to storage account: %w", err)
+	}
+
+	if err := s.waitForDataPlane(ctx, identityName, dataPlaneProbe{
+		audience: "https://storage.azure.com",
+		url:      storageProbeURL(account, storageService, container),
+		headers: map[string]string{
+			"x-ms-version": "2023-11-03",
+			"Accept":       "application/json;odata=nometadata",
+		},
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully bound storage account '%s' to identity '%s'\n", account, identityName)
+
+	return nil
+}
+
+// storageRole returns the data-plane role granting the access level on the storage service
+func storageRole(storageService, access string) (string, error) {
+	roles, ok := storageRoles[storageService]
+	if !ok {
+		return "", fmt.Errorf("unsupported storage service '%s', expected blob, queue or table", storageService)
+	}
+
+	role, ok := roles[access]
+	if !ok {
+		return "", fmt.Errorf("unsupported access level '%s', expected read or write", access)
+	}
+
+	return role, nil
+}
+
+func storageScope(accountID, storageService, container string) string {
+	if container == "" {
+		return accountID
+	}
+
+	return fmt.Sprintf("%s/%s/%s", accountID, storageContainerScopes[storageService], container)
+}
+
+// storageProbeURL returns a read-only data-plane request on the account, or on the container, queue or
+// table the role was scoped to
+func storageProbeURL(account, storageService, container string) string {
+	endpoint := fmt.Sprintf("https://%s.%s.core.windows.net", account, storageService)
+
+	switch {
+	case storageService == "blob" && container != "":
+		return fmt.Sprintf("%s/%s?restype=container&comp=list&maxresults=1", endpoint, container)
+	case storageService == "queue" && container != "":
+		return fmt.Sprintf("%s/%s/messages?peekonly=true", endpoint, container)
+	case storageService == "table" && container != "":
+		return fmt.Sprintf("%s/%s()?$top=1", endpoint, container)
+	case storageService == "table":
+		return endpoint + "/Tables?$top=1"
+	default:
+		return endpoint + "/?comp=list&maxresults=1"
+	}
+}
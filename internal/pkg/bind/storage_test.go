@@ -0,0 +1,63 @@
+package bind
+
+import "testing"
+
+func TestStorageRole(t *testing.T) {
+	tests := []struct {
+		service, access, role string
+	}{
+		{"blob", "read", "Storage Blob Data Reader"},
+		{"blob", "write", "Storage Blob Data Contributor"},
+		{"queue", "read", "Storage Queue Data Reader"},
+		{"table", "write", "Storage Table Data Contributor"},
+	}
+
+	for _, tt := range tests {
+		role, err := storageRole(tt.service, tt.access)
+		if err != nil {
+			t.Errorf("Failed to get role for %s/%s: %v", tt.service, tt.access, err)
+			continue
+		}
+		if role != tt.role {
+			t.Errorf("Expected role for %s/%s to be '%s', got '%s'", tt.service, tt.access, tt.role, role)
+		}
+	}
+
+	if _, err := storageRole("file", "read"); err == nil {
+		t.Error("Expected unsupported service to be rejected")
+	}
+
+	if _, err := storageRole("blob", "admin"); err == nil {
+		t.Error("Expected unsupported access level to be rejected")
+	}
+}
+
+func TestStorageScope(t *testing.T) {
+	accountID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/acct"
+
+	if scope := storageScope(accountID, "blob", ""); scope != accountID {
+		t.Errorf("Expected account scope, got '%s'", scope)
+	}
+
+	expected := accountID + "/queueServices/default/queues/jobs"
+	if scope := storageScope(accountID, "queue", "jobs"); scope != expected {
+		t.Errorf("Expected scope '%s', got '%s'", expected, scope)
+	}
+}
+
+func TestStorageProbeURL(t *testing.T) {
+	tests := []struct {
+		service, container, url string
+	}{
+		{"blob", "", "https://mystorage.blob.core.windows.net/?comp=list&maxresults=1"},
+		{"blob", "uploads", "https://mystorage.blob.core.windows.net/uploads?restype=container&comp=list&maxresults=1"},
+		{"queue", "jobs", "https://mystorage.queue.core.windows.net/jobs/messages?peekonly=true"},
+		{"table", "", "https://mystorage.table.core.windows.net/Tables?$top=1"},
+	}
+
+	for _, tt := range tests {
+		if url := storageProbeURL("mystorage", tt.service, tt.container); url != tt.url {
+			t.Errorf("Expected probe URL '%s', got '%s'", tt.url, url)
+		}
+	}
+}
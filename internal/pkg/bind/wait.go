@@ -0,0 +1,321 @@
+package bind
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/kube"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+const (
+	// DefaultWaitTimeout is how long --wait waits for a binding to take effect
+	DefaultWaitTimeout = 5 * time.Minute
+
+	waitInterval = 15 * time.Second
+
+	// probePodTimeout is how long a probe pod can take to start and finish
+	probePodTimeout  = 2 * time.Minute
+	probePodInterval = 2 * time.Second
+)
+
+// imagePullFailures are the reasons a probe pod waits for an image that cannot be pulled
+var imagePullFailures = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// dataPlaneProbe is a request made with a token of the identity to check that a binding has taken effect
+type dataPlaneProbe struct {
+	// audience is the resource the token is requested for
+	audience string
+	url      string
+	headers  map[string]string
+	// cosmosDB sends the token in the format expected by the Cosmos DB data plane
+	cosmosDB bool
+}
+
+// Options are the settings shared by every binding
+type Options struct {
+	// WaitTimeout makes bindings wait until the identity's access has taken effect, for at most this long.
+	// Zero disables waiting.
+	WaitTimeout time.Duration
+	// Namespace is the Kubernetes namespace of the identity's service account, where access checks run
+	Namespace string
+	// ProbeImage is the image of the pod checking data-plane access, it needs sh and curl
+	ProbeImage string
+}
+
+// Configure applies the options shared by every binding
+func (s *service) Configure(opts Options) {
+	s.waitTimeout = opts.WaitTimeout
+	s.namespace = opts.Namespace
+	s.probeImage = opts.ProbeImage
+}
+
+// waitForDataPlane calls the data plane as the identity until the request is authorized. The request is
+// made from a pod running as the identity's Kubernetes service account in the selected cluster, which is
+// how the SpinApp itself authenticates.
+func (s *service) waitForDataPlane(ctx context.Context, identityName string, probe dataPlaneProbe) error {
+	if s.waitTimeout == 0 {
+		return nil
+	}
+
+	if err := kube.UseCurrentCluster(s.subscriptionID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Waiting up to %s for access to take effect for identity '%s'...\n", s.waitTimeout, identityName)
+
+	return poll(ctx, s.waitTimeout, func() (bool, error) {
+		status, err := runProbe(s.namespace, s.probeImage, identityName, probe)
+		if err != nil {
+			return false, err
+		}
+
+		if !probeAuthorized(status) {
+			fmt.Printf("Access not effective yet (HTTP %s), retrying in %s...\n", status, waitInterval)
+			return false, nil
+		}
+
+		fmt.Println("Access is effective")
+		return true, nil
+	})
+}
+
+// waitForRoleAssignment waits until the role assignment is visible, for services without a data-plane check.
+// Visibility in Azure Resource Manager does not mean the data plane already honors the assignment.
+func (s *service) waitForRoleAssignment(ctx context.Context, identityPrincipalID, role, scope string) error {
+	if s.waitTimeout == 0 {
+		return nil
+	}
+
+	fmt.Printf("Waiting up to %s for role '%s' to be visible at scope '%s'...\n", s.waitTimeout, role, scope)
+
+	err := poll(ctx, s.waitTimeout, func() (bool, error) {
+		output, err := s.run("failed to list role assignments",
+			"role", "assignment", "list",
+			"--assignee", identityPrincipalID,
+			"--role", role,
+			"--scope", scope,
+			"--subscription", s.subscriptionID,
+			"--query", "[].id",
+			"--output", "tsv",
+		)
+		return output != "", err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Role assignment is visible. Data-plane access is not checked for this service and can take up to 10 more minutes to take effect")
+	return nil
+}
+
+// poll calls check until it succeeds, fails or the timeout expires
+func poll(ctx context.Context, timeout time.Duration, check func() (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("access did not take effect within %s, Azure role assignments can take up to 10 minutes to propagate", timeout)
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+// runProbe makes the probe request from a short-lived pod and returns the HTTP status code
+func runProbe(namespace, image, identityName string, probe dataPlaneProbe) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+	if image == "" {
+		image = config.DefaultProbeImage
+	}
+
+	overrides, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"spec": map[string]interface{}{
+			"serviceAccountName": identityName,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize probe pod: %w", err)
+	}
+
+	// Each attempt uses a new name, so that it cannot collide with a pod created by an earlier attempt
+	// or by another probe, and a collision is retried like a transient failure
+	var name string
+	err = retry.Do("kubectl run access check pod", func() error {
+		var err error
+		name, err = probePodName()
+		if err != nil {
+			return err
+		}
+
+		cmd := exec.Command(
+			"kubectl", "run", name,
+			"-n", namespace,
+			"--restart", "Never",
+			"--image", image,
+			"--labels", "azure.workload.identity/use=true",
+			"--overrides", string(overrides),
+			"--command", "--", "sh", "-c", probeScript(probe),
+		)
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			cliErr := clierror.New(err, output)
+			if strings.Contains(cliErr.Output, "AlreadyExists") {
+				cliErr.Kind = clierror.KindTransient
+			}
+			return cliErr
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to run access check as service account '%s': %w", identityName, err)
+	}
+	defer deleteProbePod(name, namespace)
+
+	if err := waitForProbePod(name, namespace, image); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("kubectl", "logs", name, "-n", namespace)
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to read access check result: %w", clierror.New(err, stderr))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// waitForProbePod waits until the probe pod has finished, failing early when its image cannot be pulled
+func waitForProbePod(name, namespace, image string) error {
+	deadline := time.Now().Add(probePodTimeout)
+	for {
+		cmd := exec.Command(
+			"kubectl", "get", "pod", name,
+			"-n", namespace,
+			"-o", "jsonpath={.status.phase} {.status.containerStatuses[0].state.waiting.reason}",
+		)
+		output, stderr, err := retry.Output(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to get access check pod '%s': %w", name, clierror.New(err, stderr))
+		}
+
+		phase, reason := probePodState(string(output))
+		switch {
+		case imagePullFailures[reason]:
+			return fmt.Errorf("access check pod cannot pull image '%s' (%s), make the image available to the cluster "+
+				"or use another image with sh and curl: spin azure config set probe-image <image>", image, reason)
+		case phase == "Succeeded":
+			return nil
+		case phase == "Failed":
+			return fmt.Errorf("access check pod '%s' failed, check it with 'kubectl logs %s -n %s'", name, name, namespace)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("access check pod '%s' did not finish within %s (phase %s), check it with 'kubectl describe pod %s -n %s'",
+				name, probePodTimeout, phase, name, namespace)
+		}
+		time.Sleep(probePodInterval)
+	}
+}
+
+// probePodName returns a pod name with a random suffix, like the names Kubernetes generates
+func probePodName() (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate access check pod name: %w", err)
+	}
+
+	return "spin-azure-probe-" + hex.EncodeToString(suffix), nil
+}
+
+// probePodState splits the phase and waiting reason printed for the probe pod
+func probePodState(output string) (string, string) {
+	fields := strings.Fields(output)
+	switch len(fields) {
+	case 0:
+		return "", ""
+	case 1:
+		return fields[0], ""
+	default:
+		return fields[0], fields[1]
+	}
+}
+
+func deleteProbePod(name, namespace string) {
+	cmd := exec.Command("kubectl", "delete", "pod", name, "-n", namespace, "--ignore-not-found", "--wait=false")
+	if output, err := retry.CombinedOutput(cmd); err != nil {
+		fmt.Printf("Warning: failed to delete access check pod '%s': %v\n", name, clierror.New(err, output))
+	}
+}
+
+// probeScript exchanges the pod's federated token for an Entra token and prints the status code of the probe request
+func probeScript(probe dataPlaneProbe) string {
+	var script strings.Builder
+	fmt.Fprintf(&script, `token=$(curl -s -X POST "${AZURE_AUTHORITY_HOST}${AZURE_TENANT_ID}/oauth2/v2.0/token" `+
+		`-d grant_type=client_credentials -d "client_id=${AZURE_CLIENT_ID}" `+
+		`--data-urlencode client_assertion_type=urn:ietf:params:oauth:client-assertion-type:jwt-bearer `+
+		`--data-urlencode "client_assertion@${AZURE_FEDERATED_TOKEN_FILE}" `+
+		`--data-urlencode scope=%s/.default | sed -n 's/.*"access_token":"\([^"]*\)".*/\1/p')`+"\n", probe.audience)
+
+	authorization := "Bearer ${token}"
+	if probe.cosmosDB {
+		authorization = url.QueryEscape("type=aad&ver=1.0&sig=") + "${token}"
+		fmt.Fprintf(&script, "date=$(date -u '+%%a, %%d %%b %%Y %%H:%%M:%%S GMT')\n")
+	}
+
+	fmt.Fprintf(&script, `curl -s -o /dev/null -w '%%{http_code}' -H "Authorization: %s"`, authorization)
+	if probe.cosmosDB {
+		script.WriteString(` -H "x-ms-date: ${date}"`)
+	}
+	for _, name := range sortedKeys(probe.headers) {
+		fmt.Fprintf(&script, ` -H '%s: %s'`, name, probe.headers[name])
+	}
+	fmt.Fprintf(&script, ` '%s'`, probe.url)
+
+	return script.String()
+}
+
+// probeAuthorized reports whether the status code of a probe request shows the identity has access.
+// Not found means the request was authorized but the checked data does not exist yet.
+func probeAuthorized(status string) bool {
+	return strings.HasPrefix(status, "2") || status == "404"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,84 @@
+package bind
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProbeAuthorized(t *testing.T) {
+	for _, status := range []string{"200", "204", "404"} {
+		if !probeAuthorized(status) {
+			t.Errorf("Expected HTTP %s to show access", status)
+		}
+	}
+
+	for _, status := range []string{"401", "403", "000"} {
+		if probeAuthorized(status) {
+			t.Errorf("Expected HTTP %s not to show access", status)
+		}
+	}
+}
+
+func TestProbeScript(t *testing.T) {
+	script := probeScript(dataPlaneProbe{
+		audience: "https://cosmos.azure.com",
+		url:      "https://my-cosmos.documents.azure.com:443/dbs",
+		headers:  map[string]string{"x-ms-version": "2018-12-31"},
+		cosmosDB: true,
+	})
+
+	for _, expected := range []string{
+		"scope=https://cosmos.azure.com/.default",
+		`-H "Authorization: type%3Daad%26ver%3D1.0%26sig%3D${token}"`,
+		`-H "x-ms-date: ${date}"`,
+		"-H 'x-ms-version: 2018-12-31'",
+		"'https://my-cosmos.documents.azure.com:443/dbs'",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("Expected probe script to contain %s, got:\n%s", expected, script)
+		}
+	}
+}
+
+func TestProbePodState(t *testing.T) {
+	tests := []struct {
+		output string
+		phase  string
+		reason string
+	}{
+		{"Pending ImagePullBackOff", "Pending", "ImagePullBackOff"},
+		{"Pending ContainerCreating", "Pending", "ContainerCreating"},
+		{"Succeeded ", "Succeeded", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		phase, reason := probePodState(tt.output)
+		if phase != tt.phase || reason != tt.reason {
+			t.Errorf("probePodState(%q) = %q, %q, expected %q, %q", tt.output, phase, reason, tt.phase, tt.reason)
+		}
+	}
+
+	if !imagePullFailures["ImagePullBackOff"] || imagePullFailures["ContainerCreating"] {
+		t.Error("Expected only image pull failures to stop the access check")
+	}
+}
+
+func TestProbePodName(t *testing.T) {
+	first, err := probePodName()
+	if err != nil {
+		t.Fatalf("Failed to generate pod name: %v", err)
+	}
+	second, err := probePodName()
+	if err != nil {
+		t.Fatalf("Failed to generate pod name: %v", err)
+	}
+
+	if first == second {
+		t.Errorf("Expected pod names to differ, got '%s' twice", first)
+	}
+
+	if !strings.HasPrefix(first, "spin-azure-probe-") || len(first) != len("spin-azure-probe-")+16 {
+		t.Errorf("Unexpected pod name '%s'", first)
+	}
+}
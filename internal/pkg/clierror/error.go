@@ -0,0 +1,196 @@
+package clierror
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kind is the kind of failure of an az, kubectl or helm command
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindAuthorizationFailed
+	KindResourceNotFound
+	KindQuotaExceeded
+	KindLoginExpired
+	KindProviderNotRegistered
+	KindReleaseExists
+	KindClusterUnreachable
+	KindThrottled
+	KindTransient
+)
+
+// Sentinel errors matching classified errors of each kind with errors.Is
+var (
+	ErrAuthorizationFailed   = errors.New("authorization failed")
+	ErrResourceNotFound      = errors.New("resource not found")
+	ErrQuotaExceeded         = errors.New("quota exceeded")
+	ErrLoginExpired          = errors.New("login expired")
+	ErrProviderNotRegistered = errors.New("resource provider not registered")
+	ErrReleaseExists         = errors.New("helm release already exists")
+	ErrClusterUnreachable    = errors.New("cluster unreachable")
+	ErrThrottled             = errors.New("request throttled")
+	ErrTransient             = errors.New("transient failure")
+)
+
+var sentinels = map[Kind]error{
+	KindAuthorizationFailed:   ErrAuthorizationFailed,
+	KindResourceNotFound:      ErrResourceNotFound,
+	KindQuotaExceeded:         ErrQuotaExceeded,
+	KindLoginExpired:          ErrLoginExpired,
+	KindProviderNotRegistered: ErrProviderNotRegistered,
+	KindReleaseExists:         ErrReleaseExists,
+	KindClusterUnreachable:    ErrClusterUnreachable,
+	KindThrottled:             ErrThrottled,
+	KindTransient:             ErrTransient,
+}
+
+// Error is the failure of an external command together with its output and, for recognised
+// failures, a hint on how to fix it
+type Error struct {
+	Kind   Kind
+	Output string
+	Hint   string
+	Err    error
+}
+
+type classifier struct {
+	kind    Kind
+	pattern *regexp.Regexp
+	hint    func(match []string) string
+}
+
+var providerNamespacePattern = regexp.MustCompile(`namespace '([^']+)'`)
+
+// classifiers are tried in order, the first match wins. Login failures come first as they are often
+// reported together with authorization errors.
+var classifiers = []classifier{
+	{
+		kind:    KindLoginExpired,
+		pattern: regexp.MustCompile(`(?i)AADSTS70043|AADSTS700082|AADSTS50173|refresh token has expired|Please run 'az login'|az login --scope|Interactive authentication is needed`),
+		hint:    staticHint("your Azure login has expired, run 'spin azure login' and try again"),
+	},
+	{
+		kind:    KindProviderNotRegistered,
+		pattern: regexp.MustCompile(`(?i)MissingSubscriptionRegistration|not registered to use namespace`),
+		hint: func(match []string) string {
+			namespace := "Microsoft.ContainerService"
+			if m := providerNamespacePattern.FindStringSubmatch(match[0]); m != nil {
+				namespace = m[1]
+			}
+			return fmt.Sprintf("the subscription is not registered for '%s', run 'az provider register --namespace %s --wait' and try again", namespace, namespace)
+		},
+	},
+	{
+		kind:    KindQuotaExceeded,
+		pattern: regexp.MustCompile(`(?i)QuotaExceeded|InsufficientQuota|exceeding approved .* quota|quota .* exceeded`),
+		hint:    staticHint("the subscription has reached a quota, request a quota increase or use a different location or VM size"),
+	},
+	{
+		kind:    KindAuthorizationFailed,
+		pattern: regexp.MustCompile(`(?i)AuthorizationFailed|does not have authorization to perform action|LinkedAuthorizationFailed|Error from server \(Forbidden\)`),
+		hint:    staticHint("the signed-in account lacks permission for this operation, ask an Owner or User Access Administrator of the subscription or resource group for access"),
+	},
+	{
+		kind:    KindResourceNotFound,
+		pattern: regexp.MustCompile(`(?i)ResourceNotFound|ResourceGroupNotFound|\(NotFound\)|Error from server \(NotFound\)`),
+		hint:    staticHint("check the name and resource group, and that the current subscription is the right one ('spin azure config show')"),
+	},
+	{
+		kind:    KindReleaseExists,
+		pattern: regexp.MustCompile(`(?i)cannot re-use a name that is still in use`),
+		hint:    staticHint("a Helm release with this name is already installed, inspect it with 'helm list -A' and uninstall it with 'helm uninstall' if it is broken"),
+	},
+	{
+		kind:    KindThrottled,
+		pattern: regexp.MustCompile(`(?i)TooManyRequests|\(429\)|Status code: 429|throttl`),
+		hint:    staticHint("Azure is throttling requests, wait a minute and try again"),
+	},
+	{
+		kind: KindTransient,
+		pattern: regexp.MustCompile(`(?i)ConcurrentFederatedIdentityCredentialsWritesForSingleManagedIdentity|AnotherOperationInProgress|` +
+			`InternalServerError|ServiceUnavailable|GatewayTimeout|RetryableError|the server is currently unable to handle the request|` +
+			`etcdserver: request timed out|TLS handshake timeout|i/o timeout|connection reset by peer`),
+		hint: staticHint("this is usually a temporary failure, try again"),
+	},
+	{
+		kind:    KindClusterUnreachable,
+		pattern: regexp.MustCompile(`(?i)The connection to the server|Unable to connect to the server|Kubernetes cluster unreachable`),
+		hint:    staticHint("the cluster API server cannot be reached, check that the cluster is running and refresh its credentials with 'spin azure cluster use'"),
+	},
+}
+
+func staticHint(hint string) func([]string) string {
+	return func([]string) string {
+		return hint
+	}
+}
+
+// New classifies the failure of an external command from its output. Unrecognised failures are
+// returned as an Error of KindUnknown without a hint.
+func New(err error, output []byte) *Error {
+	e := &Error{
+		Kind:   KindUnknown,
+		Output: string(output),
+		Err:    err,
+	}
+
+	for _, c := range classifiers {
+		if match := c.pattern.FindStringSubmatch(e.Output); match != nil {
+			e.Kind = c.kind
+			e.Hint = c.hint([]string{lineContaining(e.Output, match[0])})
+			break
+		}
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("%v\nOutput: %s", e.Err, e.Output)
+	if e.Hint != "" {
+		message += "\nHint: " + e.Hint
+	}
+
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the sentinel error of the error's kind
+func (e *Error) Is(target error) bool {
+	sentinel, ok := sentinels[e.Kind]
+	return ok && sentinel == target
+}
+
+// Retryable reports whether running the command again may succeed. An unreachable cluster is retried
+// because the API server of a new cluster can take a while to accept connections.
+func (e *Error) Retryable() bool {
+	return e.Kind == KindThrottled || e.Kind == KindTransient || e.Kind == KindClusterUnreachable
+}
+
+// KindOf returns the kind of the first classified error in err's chain
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	return KindUnknown
+}
+
+// lineContaining returns the line of the output containing the match, for hints that need context
+func lineContaining(output, match string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, match) {
+			return line
+		}
+	}
+
+	return match
+}
@@ -0,0 +1,116 @@
+package clierror
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	tests := []struct {
+		output   string
+		kind     Kind
+		sentinel error
+		hint     string
+	}{
+		{
+			output:   "(AuthorizationFailed) The client 'user@example.com' does not have authorization to perform action 'Microsoft.Authorization/roleAssignments/write'",
+			kind:     KindAuthorizationFailed,
+			sentinel: ErrAuthorizationFailed,
+			hint:     "lacks permission",
+		},
+		{
+			output:   "(ResourceGroupNotFound) Resource group 'my-rg' could not be found.",
+			kind:     KindResourceNotFound,
+			sentinel: ErrResourceNotFound,
+			hint:     "check the name",
+		},
+		{
+			output:   "(QuotaExceeded) Operation could not be completed as it results in exceeding approved standardDSv3Family Cores quota.",
+			kind:     KindQuotaExceeded,
+			sentinel: ErrQuotaExceeded,
+			hint:     "quota increase",
+		},
+		{
+			output:   "AADSTS700082: The refresh token has expired due to inactivity.",
+			kind:     KindLoginExpired,
+			sentinel: ErrLoginExpired,
+			hint:     "spin azure login",
+		},
+		{
+			output:   "(MissingSubscriptionRegistration) The subscription is not registered to use namespace 'Microsoft.ContainerService'.",
+			kind:     KindProviderNotRegistered,
+			sentinel: ErrProviderNotRegistered,
+			hint:     "az provider register --namespace Microsoft.ContainerService",
+		},
+		{
+			output:   "Error: INSTALLATION FAILED: cannot re-use a name that is still in use",
+			kind:     KindReleaseExists,
+			sentinel: ErrReleaseExists,
+			hint:     "helm list -A",
+		},
+		{
+			output:   "The connection to the server localhost:8080 was refused - did you specify the right host or port? dial tcp 127.0.0.1:8080: connect: connection refused",
+			kind:     KindClusterUnreachable,
+			sentinel: ErrClusterUnreachable,
+			hint:     "spin azure cluster use",
+		},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("failed to run command: %w", New(exitErr, []byte(tt.output)))
+
+		if kind := KindOf(err); kind != tt.kind {
+			t.Errorf("Expected kind %d for output %q, got %d", tt.kind, tt.output, kind)
+		}
+		if !errors.Is(err, tt.sentinel) {
+			t.Errorf("Expected error for output %q to match %v", tt.output, tt.sentinel)
+		}
+		if !errors.Is(err, exitErr) {
+			t.Errorf("Expected error for output %q to wrap the command error", tt.output)
+		}
+		if !strings.Contains(err.Error(), tt.hint) {
+			t.Errorf("Expected error for output %q to contain hint %q, got %q", tt.output, tt.hint, err.Error())
+		}
+	}
+}
+
+func TestNewUnknown(t *testing.T) {
+	err := New(errors.New("exit status 2"), []byte("something else went wrong"))
+
+	if err.Kind != KindUnknown {
+		t.Errorf("Expected unknown kind, got %d", err.Kind)
+	}
+	if errors.Is(err, ErrResourceNotFound) {
+		t.Error("Expected unknown error not to match a sentinel")
+	}
+	if err.Error() != "exit status 2\nOutput: something else went wrong" {
+		t.Errorf("Unexpected message for unknown error: %q", err.Error())
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		output    string
+		retryable bool
+	}{
+		{"ERROR: (TooManyRequests) The request is being throttled.", true},
+		{"ERROR: (ConcurrentFederatedIdentityCredentialsWritesForSingleManagedIdentity) Too many Federated Identity Credentials are written concurrently", true},
+		{"Error from server: etcdserver: request timed out", true},
+		{"Unable to connect to the server: dial tcp 10.0.0.1:443: i/o timeout", true},
+		{"ERROR: (AuthorizationFailed) The client does not have authorization", false},
+		{"ERROR: (ResourceGroupNotFound) Resource group 'my-rg' could not be found.", false},
+		{"psql: error: connection to server at \"db.postgres.database.azure.com\" failed: Connection refused", false},
+		{"ERROR: HTTPSConnectionPool(host='myregistry.azurecr.io', port=443): Failed to resolve: no such host", false},
+	}
+
+	for _, test := range tests {
+		err := New(errors.New("exit status 1"), []byte(test.output))
+		if err.Retryable() != test.retryable {
+			t.Errorf("Retryable() for %q = %v, expected %v", test.output, err.Retryable(), test.retryable)
+		}
+	}
+}
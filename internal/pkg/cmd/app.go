@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/app"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+)
+
+func NewAppCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "app",
+		Short: "Manage Spin applications deployed to AKS",
+		Long:  `List, inspect, scale and delete SpinApps deployed to the current AKS cluster.`,
+	}
+
+	cmd.AddCommand(newAppListCommand())
+	cmd.AddCommand(newAppStatusCommand())
+	cmd.AddCommand(newAppLogsCommand())
+	cmd.AddCommand(newAppScaleCommand())
+	cmd.AddCommand(newAppDeleteCommand())
+
+	return cmd
+}
+
+// newAppService creates the app service, defaulting the namespace to the configured one when it is not set
+func newAppService(namespace *string) (*app.Service, error) {
+	credential, err := config.GetAzureCredential()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure credential: %w", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.SubscriptionID == "" {
+		return nil, fmt.Errorf("subscription ID not set, please set it using `spin azure login`")
+	}
+
+	if *namespace == "" {
+		*namespace = cfg.GetNamespace()
+	}
+
+	return app.NewService(credential, cfg.SubscriptionID), nil
+}
+
+func newAppListCommand() *cobra.Command {
+	var namespace, outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List SpinApps in the current cluster",
+		Long:  `List SpinApps in the current cluster along with the service account and managed identity each app runs as.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appService, err := newAppService(&namespace)
+			if err != nil {
+				return err
+			}
+
+			apps, err := appService.List(context.Background(), namespace)
+			if err != nil {
+				return fmt.Errorf("failed to list SpinApps: %w", err)
+			}
+
+			if outputFormat == "json" {
+				return printJSON(apps)
+			}
+
+			if len(apps) == 0 {
+				fmt.Printf("No SpinApps found in namespace '%s'\n", namespace)
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tREADY\tSERVICE ACCOUNT\tIDENTITY\tIMAGE")
+			for _, a := range apps {
+				fmt.Fprintf(w, "%s\t%d/%d\t%s\t%s\t%s\n", a.Name, a.ReadyReplicas, a.Replicas, a.ServiceAccount, identityDisplayName(a), a.Image)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the SpinApps (defaults to the namespace config key)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text|json)")
+
+	return cmd
+}
+
+func newAppStatusCommand() *cobra.Command {
+	var namespace, outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "status <name>",
+		Short: "Show the status of a SpinApp",
+		Long:  `Show the replicas, conditions, service account and managed identity of a SpinApp.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appService, err := newAppService(&namespace)
+			if err != nil {
+				return err
+			}
+
+			a, err := appService.Get(context.Background(), args[0], namespace)
+			if err != nil {
+				return fmt.Errorf("failed to get SpinApp status: %w", err)
+			}
+
+			if outputFormat == "json" {
+				return printJSON(a)
+			}
+
+			fmt.Printf("SpinApp: %s\n", a.Name)
+			fmt.Printf("  Namespace: %s\n", a.Namespace)
+			fmt.Printf("  Image: %s\n", a.Image)
+			fmt.Printf("  Executor: %s\n", a.Executor)
+			fmt.Printf("  Ready Replicas: %d/%d\n", a.ReadyReplicas, a.Replicas)
+			fmt.Printf("  Service Account: %s\n", a.ServiceAccount)
+			fmt.Printf("  Identity: %s\n", identityDisplayName(*a))
+			if a.IdentityClientID != "" {
+				fmt.Printf("  Identity Client ID: %s\n", a.IdentityClientID)
+			}
+			if len(a.Conditions) > 0 {
+				fmt.Println("  Conditions:")
+				for _, c := range a.Conditions {
+					fmt.Printf("    %s=%s", c.Type, c.Status)
+					if c.Message != "" {
+						fmt.Printf(" (%s)", c.Message)
+					}
+					fmt.Println()
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the SpinApp (defaults to the namespace config key)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text|json)")
+
+	return cmd
+}
+
+func newAppLogsCommand() *cobra.Command {
+	var namespace string
+	var follow bool
+
+	cmd := &cobra.Command{
+		Use:   "logs <name>",
+		Short: "Show the logs of a SpinApp",
+		Long:  `Show the logs of all pods of a SpinApp.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appService, err := newAppService(&namespace)
+			if err != nil {
+				return err
+			}
+
+			if err := appService.Logs(cmd.Context(), args[0], namespace, follow, cmd.OutOrStdout()); err != nil {
+				return fmt.Errorf("failed to get SpinApp logs: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the SpinApp (defaults to the namespace config key)")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Stream the logs")
+
+	return cmd
+}
+
+func newAppScaleCommand() *cobra.Command {
+	var namespace string
+	var replicas int
+
+	cmd := &cobra.Command{
+		Use:   "scale <name>",
+		Short: "Scale a SpinApp",
+		Long: `Set the number of replicas of a SpinApp.
+Autoscaled SpinApps are rejected, change their replica range with 'spin azure deploy --autoscale' instead.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if replicas < 0 {
+				return fmt.Errorf("--replicas must not be negative")
+			}
+
+			appService, err := newAppService(&namespace)
+			if err != nil {
+				return err
+			}
+
+			if err := appService.Scale(context.Background(), args[0], namespace, replicas); err != nil {
+				return fmt.Errorf("failed to scale SpinApp: %w", err)
+			}
+
+			fmt.Printf("Scaled SpinApp '%s' to %d replicas\n", args[0], replicas)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the SpinApp (defaults to the namespace config key)")
+	cmd.Flags().IntVar(&replicas, "replicas", 0, "Number of replicas (required)")
+	if err := cmd.MarkFlagRequired("replicas"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'replicas' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newAppDeleteCommand() *cobra.Command {
+	var namespace string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a SpinApp",
+		Long:  `Delete a SpinApp and the resources the Spin Operator created for it.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			appService, err := newAppService(&namespace)
+			if err != nil {
+				return err
+			}
+
+			if !yes {
+				fmt.Printf("Are you sure you want to delete SpinApp '%s' in namespace '%s'? [y/N]: ", name, namespace)
+				var response string
+				if _, err := fmt.Scanln(&response); err != nil {
+					return fmt.Errorf("failed to read response: %w", err)
+				}
+				if response != "y" && response != "Y" {
+					fmt.Println("Delete cancelled.")
+					return nil
+				}
+			}
+
+			if err := appService.Delete(context.Background(), name, namespace); err != nil {
+				return fmt.Errorf("failed to delete SpinApp: %w", err)
+			}
+
+			fmt.Printf("Deleted SpinApp '%s'\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the SpinApp (defaults to the namespace config key)")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Delete without confirmation")
+
+	return cmd
+}
+
+func identityDisplayName(a app.App) string {
+	switch {
+	case a.IdentityName != "":
+		return a.IdentityName
+	case a.IdentityClientID != "":
+		return a.IdentityClientID
+	default:
+		return "<none>"
+	}
+}
+
+func printJSON(v interface{}) error {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal to JSON: %w", err)
+	}
+	fmt.Println(string(jsonData))
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import "testing"
+
+func TestNewAppCommand(t *testing.T) {
+	cmd := NewAppCommand()
+	if cmd.Use != "app" {
+		t.Errorf("Expected command use to be 'app', got '%s'", cmd.Use)
+	}
+
+	for _, name := range []string{"list", "status", "logs", "scale", "delete"} {
+		if findSubcommand(cmd.Commands(), name) == nil {
+			t.Errorf("Expected to find '%s' subcommand", name)
+		}
+	}
+
+	scaleCommand := findSubcommand(cmd.Commands(), "scale")
+	if scaleCommand == nil {
+		return
+	}
+
+	if err := scaleCommand.Args(scaleCommand, []string{}); err == nil {
+		t.Error("Expected scale command to require an app name")
+	}
+
+	if scaleCommand.Flags().Lookup("replicas") == nil {
+		t.Error("Expected scale command to have 'replicas' flag")
+	}
+
+	logsCommand := findSubcommand(cmd.Commands(), "logs")
+	if logsCommand != nil && logsCommand.Flags().ShorthandLookup("f") == nil {
+		t.Error("Expected logs command to have '-f' flag")
+	}
+}
@@ -3,89 +3,781 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/spf13/cobra"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/aks"
 	"github.com/spinframework/spin-plugin-azure/internal/pkg/bind"
 	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/runtimeconfig"
 )
 
 func NewAssignRoleCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "assign-role",
 		Short: "Assign Azure RBAC roles to managed identities",
-		Long:  `Assign Azure RBAC roles to managed identities for accessing Azure services like CosmosDB.`,
+		Long: `Assign Azure RBAC roles to managed identities for accessing Azure services like CosmosDB, Key Vault, Storage, Service Bus, Event Hubs, Redis, PostgreSQL, Azure OpenAI and Container Registry.
+Any other Azure resource can be targeted with 'assign-role generic'.`,
 	}
 
 	cmd.AddCommand(newBindCosmosDBCommand())
+	cmd.AddCommand(newBindKeyVaultCommand())
+	cmd.AddCommand(newBindStorageCommand())
+	cmd.AddCommand(newBindServiceBusCommand())
+	cmd.AddCommand(newBindEventHubsCommand())
+	cmd.AddCommand(newBindRedisCommand())
+	cmd.AddCommand(newBindPostgresCommand())
+	cmd.AddCommand(newBindOpenAICommand())
+	cmd.AddCommand(newBindACRCommand())
+	cmd.AddCommand(newBindGenericCommand())
+	cmd.AddCommand(newListRoleAssignmentsCommand())
 
 	return cmd
 }
 
 func newBindCosmosDBCommand() *cobra.Command {
 	var name, resourceGroup, identityName, identityResourceGroup string
+	var location, runtimeConfigSecret, store string
+	var access bind.CosmosDBAccess
+	var create, disableLocalAuth bool
+	var waitFlags waitFlags
 
 	cmd := &cobra.Command{
 		Use:   "cosmosdb",
 		Short: "Assign Azure roles for CosmosDB access",
-		Long:  `Assign the necessary Azure RBAC roles to a managed identity for accessing an Azure CosmosDB instance.`,
+		Long: `Assign a Cosmos DB data-plane role to a managed identity for accessing an Azure CosmosDB instance.
+The role is the built-in Data Reader or Data Contributor, an existing role definition, or a custom role created from --data-actions,
+and can be scoped to the whole account, a database or a container.
+With --create, a serverless NoSQL account with key authentication disabled is created if needed, along with the
+database and container used by Spin's key-value store. An existing account keeps its authentication settings,
+use --disable-local-auth to disable key authentication on it.
+With --runtime-config-secret, a Spin key-value store using the container is written to the runtime config in that Kubernetes Secret.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			credential, err := config.GetAzureCredential()
+			credential, cfg, err := loadBindContext("CosmosDB", &resourceGroup, &identityName, &identityResourceGroup)
 			if err != nil {
-				return fmt.Errorf("failed to get Azure credential: %w", err)
+				return err
 			}
 
-			cfg, err := config.LoadConfig()
-			if err != nil {
-				return fmt.Errorf("failed to load config: %w", err)
+			cosmosDBService := newBindService(credential, cfg, waitFlags, bind.NewCosmosDBService)
+
+			if runtimeConfigSecret != "" && !create && (access.Database == "" || access.Container == "") {
+				return fmt.Errorf("--runtime-config-secret requires --database and --container, or --create")
 			}
 
-			if cfg.SubscriptionID == "" {
-				return fmt.Errorf("subscription ID not set, please set it using `spin azure login`")
+			ctx := context.Background()
+
+			var account *bind.CosmosDBAccount
+			if create {
+				account, err = cosmosDBService.CreateCosmosDB(ctx, name, resourceGroup, location, access.Database, access.Container)
+				if err != nil {
+					return fmt.Errorf("failed to create CosmosDB: %w", err)
+				}
 			}
 
-			if resourceGroup == "" {
+			if disableLocalAuth {
+				if err := cosmosDBService.DisableLocalAuth(ctx, name, resourceGroup); err != nil {
+					return fmt.Errorf("failed to disable local authentication on CosmosDB: %w", err)
+				}
+			}
 
-				resourceGroup = cfg.ResourceGroup
+			fmt.Printf("Assigning CosmosDB role '%s' to identity '%s' (in resource group '%s') for CosmosDB account '%s' (in resource group '%s')...\n",
+				access.Role, identityName, identityResourceGroup, name, resourceGroup)
+
+			if err := cosmosDBService.BindCosmosDB(ctx, name, resourceGroup, access, identityName, identityResourceGroup); err != nil {
+				return fmt.Errorf("failed to assign role to CosmosDB: %w", err)
 			}
 
-			if resourceGroup == "" {
-				return fmt.Errorf("resource group for CosmosDB not set, please set it using --resource-group")
+			fmt.Printf("Successfully assigned roles to CosmosDB '%s'\n", name)
+			recordBinding(config.Binding{Service: "cosmosdb", Name: name, ResourceGroup: resourceGroup, Role: access.Role, Identity: identityName})
+			if account != nil {
+				fmt.Printf("Endpoint: %s\n", account.Endpoint)
+				fmt.Printf("Database: %s\n", account.Database)
+				fmt.Printf("Container: %s\n", account.Container)
 			}
 
-			if identityResourceGroup == "" {
-				identityResourceGroup = resourceGroup
+			if runtimeConfigSecret != "" {
+				database, container := access.Database, access.Container
+				if account != nil {
+					database, container = account.Database, account.Container
+				}
+
+				section := runtimeconfig.CosmosKeyValueStore(store, name, database, container)
+				if err := writeRuntimeConfig(ctx, credential, cfg, runtimeConfigSecret, section); err != nil {
+					return err
+				}
 			}
+			return nil
+		},
+	}
 
-			if identityName == "" {
-				identityName = cfg.IdentityName
+	cmd.Flags().StringVar(&name, "name", "", "Name of the CosmosDB account (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the CosmosDB account")
+	cmd.Flags().StringVar(&access.Role, "role", "contributor", "Role to assign (reader|contributor|<role-definition-id>), or the name of the custom role to create with --data-actions")
+	cmd.Flags().StringVar(&access.Database, "database", "", "Database to scope the role to")
+	cmd.Flags().StringVar(&access.Container, "container", "", "Container to scope the role to (requires --database)")
+	cmd.Flags().StringSliceVar(&access.DataActions, "data-actions", nil, "Data actions of a custom role to create or update, e.g. Microsoft.DocumentDB/databaseAccounts/sqlDatabases/containers/items/read")
+	cmd.Flags().BoolVar(&create, "create", false, "Create the account, database and container if they do not exist")
+	cmd.Flags().StringVar(&location, "location", "", "Azure region of the account created with --create (defaults to the resource group location)")
+	cmd.Flags().BoolVar(&disableLocalAuth, "disable-local-auth", false, "Disable local (key) authentication on the account, clients using the account keys stop working")
+	cmd.Flags().StringVar(&runtimeConfigSecret, "runtime-config-secret", "", "Kubernetes Secret to write a Spin key-value store using the container to")
+	cmd.Flags().StringVar(&store, "store", "default", "Label of the Spin key-value store written with --runtime-config-secret")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to assign roles to")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the CosmosDB resource group if not specified)")
+	waitFlags.register(cmd)
+	if err := cmd.MarkFlagRequired("name"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'from' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newBindKeyVaultCommand() *cobra.Command {
+	var name, resourceGroup, role, identityName, identityResourceGroup string
+	var waitFlags waitFlags
+
+	cmd := &cobra.Command{
+		Use:   "keyvault",
+		Short: "Assign Azure roles for Key Vault access",
+		Long: `Grant a managed identity access to the secrets and keys of an Azure Key Vault.
+Vaults using Azure RBAC get a role assignment, vaults using access policies get an access policy with equivalent permissions.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("Key Vault", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
 			}
 
-			if identityName == "" {
-				return fmt.Errorf("identity name not set, please set it using --identity")
+			keyVaultService := newBindService(credential, cfg, waitFlags, bind.NewKeyVaultService)
+
+			fmt.Printf("Granting '%s' to identity '%s' (in resource group '%s') for Key Vault '%s' (in resource group '%s')...\n",
+				role, identityName, identityResourceGroup, name, resourceGroup)
+
+			ctx := context.Background()
+			if err := keyVaultService.BindKeyVault(ctx, name, resourceGroup, role, identityName, identityResourceGroup); err != nil {
+				return fmt.Errorf("failed to assign role to Key Vault: %w", err)
 			}
 
-			cosmosDBService := bind.NewCosmosDBService(credential, cfg.SubscriptionID)
+			fmt.Printf("Successfully assigned roles to Key Vault '%s'\n", name)
+			recordBinding(config.Binding{Service: "keyvault", Name: name, ResourceGroup: resourceGroup, Role: role, Identity: identityName})
+			return nil
+		},
+	}
 
-			fmt.Printf("Assigning CosmosDB Data Contributor role to identity '%s' (in resource group '%s') for CosmosDB account '%s' (in resource group '%s')...\n",
-				identityName, identityResourceGroup, name, resourceGroup)
+	cmd.Flags().StringVar(&name, "name", "", "Name of the Key Vault (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the Key Vault")
+	cmd.Flags().StringVar(&role, "role", bind.DefaultKeyVaultRole, "Role to grant, e.g. 'Key Vault Secrets User' or 'Key Vault Crypto User'")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to assign roles to")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the Key Vault resource group if not specified)")
+	waitFlags.register(cmd)
+	if err := cmd.MarkFlagRequired("name"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'name' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newBindStorageCommand() *cobra.Command {
+	var account, resourceGroup, storageService, container, access, identityName, identityResourceGroup string
+	var waitFlags waitFlags
+
+	cmd := &cobra.Command{
+		Use:   "storage",
+		Short: "Assign Azure roles for Storage access",
+		Long: `Grant a managed identity read or write access to the blobs, queues or tables of an Azure Storage account.
+The role can be scoped to the whole account or to a single blob container, queue or table.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("storage account", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			storageBindService := newBindService(credential, cfg, waitFlags, bind.NewStorageService)
+
+			fmt.Printf("Granting %s access on %s storage to identity '%s' (in resource group '%s') for storage account '%s' (in resource group '%s')...\n",
+				access, storageService, identityName, identityResourceGroup, account, resourceGroup)
 
 			ctx := context.Background()
-			if err := cosmosDBService.BindCosmosDB(ctx, name, resourceGroup, identityName, identityResourceGroup); err != nil {
-				return fmt.Errorf("failed to assign role to CosmosDB: %w", err)
+			if err := storageBindService.BindStorage(ctx, account, resourceGroup, storageService, container, access, identityName, identityResourceGroup); err != nil {
+				return fmt.Errorf("failed to assign role to storage account: %w", err)
 			}
 
-			fmt.Printf("Successfully assigned roles to CosmosDB '%s'\n", name)
+			fmt.Printf("Successfully assigned roles to storage account '%s'\n", account)
+			recordBinding(config.Binding{Service: "storage", Name: account, ResourceGroup: resourceGroup, Role: storageService + " " + access, Identity: identityName})
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVar(&name, "name", "", "Name of the CosmosDB account (required)")
-	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the CosmosDB account")
+	cmd.Flags().StringVar(&account, "account", "", "Name of the storage account (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the storage account")
+	cmd.Flags().StringVar(&storageService, "service", "blob", "Storage service to grant access to (blob|queue|table)")
+	cmd.Flags().StringVar(&container, "container", "", "Blob container, queue or table to scope the role to (defaults to the whole account)")
+	cmd.Flags().StringVar(&access, "access", "read", "Access level to grant (read|write)")
 	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to assign roles to")
-	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the CosmosDB resource group if not specified)")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the storage account resource group if not specified)")
+	waitFlags.register(cmd)
+	if err := cmd.MarkFlagRequired("account"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'account' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newBindServiceBusCommand() *cobra.Command {
+	var namespace, resourceGroup, queue, topic, role, identityName, identityResourceGroup string
+	var waitFlags waitFlags
+
+	cmd := &cobra.Command{
+		Use:   "servicebus",
+		Short: "Assign Azure roles for Service Bus access",
+		Long: `Grant a managed identity the Azure Service Bus Data Sender, Data Receiver or Data Owner role
+on a Service Bus namespace, or on a single queue or topic.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("Service Bus", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			serviceBusService := newBindService(credential, cfg, waitFlags, bind.NewServiceBusService)
+
+			ctx := context.Background()
+			if err := serviceBusService.BindServiceBus(ctx, namespace, resourceGroup, queue, topic, role, identityName, identityResourceGroup); err != nil {
+				return fmt.Errorf("failed to assign role to Service Bus: %w", err)
+			}
+
+			fmt.Printf("Successfully assigned roles to Service Bus namespace '%s'\n", namespace)
+			recordBinding(config.Binding{Service: "servicebus", Name: namespace, ResourceGroup: resourceGroup, Role: role, Identity: identityName})
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Name of the Service Bus namespace (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the Service Bus namespace")
+	cmd.Flags().StringVar(&queue, "queue", "", "Queue to scope the role to")
+	cmd.Flags().StringVar(&topic, "topic", "", "Topic to scope the role to")
+	cmd.Flags().StringVar(&role, "role", "receiver", "Role to grant (sender|receiver|owner)")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to assign roles to")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the Service Bus resource group if not specified)")
+	waitFlags.register(cmd)
+	cmd.MarkFlagsMutuallyExclusive("queue", "topic")
+	if err := cmd.MarkFlagRequired("namespace"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'namespace' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newBindEventHubsCommand() *cobra.Command {
+	var namespace, resourceGroup, eventHub, role, identityName, identityResourceGroup string
+	var waitFlags waitFlags
+
+	cmd := &cobra.Command{
+		Use:   "eventhubs",
+		Short: "Assign Azure roles for Event Hubs access",
+		Long: `Grant a managed identity the Azure Event Hubs Data Sender, Data Receiver or Data Owner role
+on an Event Hubs namespace, or on a single event hub.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("Event Hubs", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			eventHubsService := newBindService(credential, cfg, waitFlags, bind.NewEventHubsService)
+
+			ctx := context.Background()
+			if err := eventHubsService.BindEventHubs(ctx, namespace, resourceGroup, eventHub, role, identityName, identityResourceGroup); err != nil {
+				return fmt.Errorf("failed to assign role to Event Hubs: %w", err)
+			}
+
+			fmt.Printf("Successfully assigned roles to Event Hubs namespace '%s'\n", namespace)
+			recordBinding(config.Binding{Service: "eventhubs", Name: namespace, ResourceGroup: resourceGroup, Role: role, Identity: identityName})
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Name of the Event Hubs namespace (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the Event Hubs namespace")
+	cmd.Flags().StringVar(&eventHub, "eventhub", "", "Event hub to scope the role to")
+	cmd.Flags().StringVar(&role, "role", "receiver", "Role to grant (sender|receiver|owner)")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to assign roles to")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the Event Hubs resource group if not specified)")
+	waitFlags.register(cmd)
+	if err := cmd.MarkFlagRequired("namespace"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'namespace' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newBindRedisCommand() *cobra.Command {
+	var name, resourceGroup, role, identityName, identityResourceGroup string
+	var waitFlags waitFlags
+	var runtimeConfigSecret, store string
+
+	cmd := &cobra.Command{
+		Use:   "redis",
+		Short: "Assign access policies for Azure Cache for Redis",
+		Long: `Grant a managed identity access to an Azure Cache for Redis using Microsoft Entra authentication.
+Entra authentication is enabled on the cache if needed, and a data access policy assignment is created for the identity.
+With --runtime-config-secret, a Spin key-value store using the cache is written to the runtime config in that Kubernetes Secret.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("Redis cache", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			redisService := newBindService(credential, cfg, waitFlags, bind.NewRedisService)
+
+			ctx := context.Background()
+			connection, err := redisService.BindRedis(ctx, name, resourceGroup, role, identityName, identityResourceGroup)
+			if err != nil {
+				return fmt.Errorf("failed to assign access policy to Redis cache: %w", err)
+			}
+
+			fmt.Printf("Successfully assigned access policy to Redis cache '%s'\n", name)
+			recordBinding(config.Binding{Service: "redis", Name: name, ResourceGroup: resourceGroup, Role: role, Identity: identityName})
+			fmt.Println("Connect with the following settings, using an Entra token for the identity as the password:")
+			fmt.Printf("  Host: %s\n", connection.HostName)
+			fmt.Printf("  Port: %d (TLS)\n", connection.Port)
+			fmt.Printf("  Username: %s\n", connection.Username)
+
+			if runtimeConfigSecret != "" {
+				url := fmt.Sprintf("rediss://%s:%d", connection.HostName, connection.Port)
+				section := runtimeconfig.RedisKeyValueStore(store, url)
+				if err := writeRuntimeConfig(ctx, credential, cfg, runtimeConfigSecret, section); err != nil {
+					return err
+				}
+				fmt.Println("Note: Spin's Redis key-value store does not acquire Entra tokens, add credentials to the url in the Secret if the cache requires them")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Name of the Azure Cache for Redis (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the Redis cache")
+	cmd.Flags().StringVar(&role, "role", "contributor", "Data access policy to assign (owner|contributor|reader)")
+	cmd.Flags().StringVar(&runtimeConfigSecret, "runtime-config-secret", "", "Kubernetes Secret to write a Spin key-value store using the cache to")
+	cmd.Flags().StringVar(&store, "store", "default", "Label of the Spin key-value store written with --runtime-config-secret")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to assign the access policy to")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the Redis cache resource group if not specified)")
+	waitFlags.register(cmd)
 	if err := cmd.MarkFlagRequired("name"); err != nil {
-		panic(fmt.Sprintf("failed to mark flag 'from' as required: %v", err))
+		panic(fmt.Sprintf("failed to mark flag 'name' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newBindPostgresCommand() *cobra.Command {
+	var name, resourceGroup, database, identityName, identityResourceGroup string
+	var waitFlags waitFlags
+	var admin, printVariables bool
+
+	cmd := &cobra.Command{
+		Use:   "postgres",
+		Short: "Grant access to Azure Database for PostgreSQL Flexible Server",
+		Long: `Grant a managed identity access to an Azure Database for PostgreSQL Flexible Server using Microsoft Entra authentication.
+By default a database role is created for the identity, which requires psql and the signed-in user to be a Microsoft Entra administrator of the server.
+With --admin the identity is added as a Microsoft Entra administrator instead.
+Unlike the CosmosDB and Redis bindings, --runtime-config-secret is not supported: Spin's runtime config has no PostgreSQL section
+and cannot hold variable values. Use --print-variables to get the connection settings as 'spin azure deploy --variable' flags.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("PostgreSQL server", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			postgresService := newBindService(credential, cfg, waitFlags, bind.NewPostgresService)
+
+			ctx := context.Background()
+			connection, err := postgresService.BindPostgres(ctx, name, resourceGroup, database, admin, identityName, identityResourceGroup)
+			if err != nil {
+				return fmt.Errorf("failed to grant access to PostgreSQL server: %w", err)
+			}
+
+			fmt.Printf("Successfully granted access to PostgreSQL server '%s'\n", name)
+			recordBinding(config.Binding{Service: "postgres", Name: name, ResourceGroup: resourceGroup, Role: postgresRole(admin), Identity: identityName})
+			fmt.Println("Connect with the following parameters, using an Entra token for the identity as the password:")
+			fmt.Printf("  host=%s port=%d dbname=%s user=%s sslmode=%s\n",
+				connection.Host, connection.Port, connection.Database, connection.User, connection.SSLMode)
+
+			if printVariables {
+				fmt.Println("Spin variables:")
+				fmt.Printf("  --variable postgres_host=%s\n", connection.Host)
+				fmt.Printf("  --variable postgres_port=%d\n", connection.Port)
+				fmt.Printf("  --variable postgres_database=%s\n", connection.Database)
+				fmt.Printf("  --variable postgres_user=%s\n", connection.User)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Name of the PostgreSQL Flexible Server (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the PostgreSQL server")
+	cmd.Flags().StringVar(&database, "database", "postgres", "Database the identity connects to")
+	cmd.Flags().BoolVar(&admin, "admin", false, "Add the identity as a Microsoft Entra administrator instead of creating a database role")
+	cmd.Flags().BoolVar(&printVariables, "print-variables", false, "Print the connection settings as Spin variables")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to grant access to")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the PostgreSQL server resource group if not specified)")
+	waitFlags.register(cmd)
+	if err := cmd.MarkFlagRequired("name"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'name' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newBindOpenAICommand() *cobra.Command {
+	var account, resourceGroup, role, deployment, identityName, identityResourceGroup string
+	var waitFlags waitFlags
+	var printVariables bool
+
+	cmd := &cobra.Command{
+		Use:   "openai",
+		Short: "Assign Azure roles for Azure OpenAI access",
+		Long: `Grant a managed identity access to an Azure OpenAI or AI Services account and list the model deployments available on it.
+With --print-variables, the endpoint and deployment name are printed as 'spin azure deploy --variable' flags.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("Azure OpenAI account", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			openAIService := newBindService(credential, cfg, waitFlags, bind.NewOpenAIService)
+
+			ctx := context.Background()
+			openAIAccount, err := openAIService.BindOpenAI(ctx, account, resourceGroup, role, identityName, identityResourceGroup)
+			if err != nil {
+				return fmt.Errorf("failed to assign role to Azure OpenAI account: %w", err)
+			}
+
+			fmt.Printf("Successfully assigned roles to Azure OpenAI account '%s'\n", account)
+			recordBinding(config.Binding{Service: "openai", Name: account, ResourceGroup: resourceGroup, Role: role, Identity: identityName})
+			fmt.Printf("Endpoint: %s\n", openAIAccount.Endpoint)
+			if len(openAIAccount.Deployments) == 0 {
+				fmt.Println("No model deployments found on the account")
+			} else {
+				fmt.Println("Model deployments:")
+				for _, d := range openAIAccount.Deployments {
+					fmt.Printf("  %s (%s %s)\n", d.Name, d.Model, d.ModelVersion)
+				}
+			}
+
+			if printVariables {
+				if deployment == "" && len(openAIAccount.Deployments) > 0 {
+					deployment = openAIAccount.Deployments[0].Name
+				}
+
+				fmt.Println("Spin variables:")
+				fmt.Printf("  --variable openai_endpoint=%s\n", openAIAccount.Endpoint)
+				if deployment != "" {
+					fmt.Printf("  --variable openai_deployment=%s\n", deployment)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&account, "account", "", "Name of the Azure OpenAI or AI Services account (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the account")
+	cmd.Flags().StringVar(&role, "role", bind.DefaultOpenAIRole, "Role to grant")
+	cmd.Flags().BoolVar(&printVariables, "print-variables", false, "Print the endpoint and deployment name as Spin variables")
+	cmd.Flags().StringVar(&deployment, "deployment", "", "Deployment to print with --print-variables (defaults to the first deployment)")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to assign roles to")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the account resource group if not specified)")
+	waitFlags.register(cmd)
+	if err := cmd.MarkFlagRequired("account"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'account' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newBindACRCommand() *cobra.Command {
+	var registry, resourceGroup string
+	var waitFlags waitFlags
+
+	cmd := &cobra.Command{
+		Use:   "acr",
+		Short: "Allow the current cluster to pull images from Azure Container Registry",
+		Long: `Grant the AcrPull role on an Azure Container Registry to the kubelet identity of the current AKS cluster,
+so that SpinApp images pushed to the registry can be pulled by the cluster nodes.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := newAzureContext()
+			if err != nil {
+				return err
+			}
+
+			if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+				return fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' or 'spin azure cluster create' first")
+			}
+
+			if resourceGroup == "" {
+				resourceGroup = cfg.ResourceGroup
+			}
+
+			aksService, err := aks.NewService(credential, cfg.SubscriptionID)
+			if err != nil {
+				return fmt.Errorf("failed to create AKS service: %w", err)
+			}
+
+			kubeletObjectID, err := aksService.GetKubeletIdentityObjectID(cfg.ClusterName, cfg.ResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			acrService := newBindService(credential, cfg, waitFlags, bind.NewACRService)
+
+			fmt.Printf("Granting AcrPull to the kubelet identity of cluster '%s' for container registry '%s' (in resource group '%s')...\n",
+				cfg.ClusterName, registry, resourceGroup)
+
+			ctx := context.Background()
+			if err := acrService.BindACR(ctx, registry, resourceGroup, kubeletObjectID); err != nil {
+				return fmt.Errorf("failed to assign role to container registry: %w", err)
+			}
+
+			fmt.Printf("Cluster '%s' can now pull images from container registry '%s'\n", cfg.ClusterName, registry)
+			recordBinding(config.Binding{Service: "acr", Name: registry, ResourceGroup: resourceGroup, Role: "AcrPull"})
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&registry, "registry", "", "Name of the Azure Container Registry (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the container registry (defaults to the resource group of the current cluster)")
+	waitFlags.register(cmd)
+	if err := cmd.MarkFlagRequired("registry"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'registry' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newBindGenericCommand() *cobra.Command {
+	var scope, role, identityName, identityResourceGroup string
+	var waitFlags waitFlags
+
+	cmd := &cobra.Command{
+		Use:   "generic",
+		Short: "Assign any Azure role at any scope",
+		Long: `Assign an Azure RBAC role to a managed identity at the scope of any subscription, resource group or resource,
+for Azure services without a dedicated assign-role command. The role can be given by name, GUID or role definition ID.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("", nil, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			genericService := newBindService(credential, cfg, waitFlags, bind.NewGenericService)
+
+			ctx := context.Background()
+			if err := genericService.BindGeneric(ctx, scope, role, identityName, identityResourceGroup); err != nil {
+				return fmt.Errorf("failed to assign role: %w", err)
+			}
+
+			recordBinding(config.Binding{Service: "generic", Name: scope, Role: role, Identity: identityName})
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&scope, "scope", "", "ID of the subscription, resource group or resource to assign the role at (required)")
+	cmd.Flags().StringVar(&role, "role", "", "Name, GUID or role definition ID of the role to assign (required)")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to assign the role to")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the configured resource group)")
+	waitFlags.register(cmd)
+	if err := cmd.MarkFlagRequired("scope"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'scope' as required: %v", err))
+	}
+	if err := cmd.MarkFlagRequired("role"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'role' as required: %v", err))
 	}
 
 	return cmd
 }
+
+func newListRoleAssignmentsCommand() *cobra.Command {
+	var identityName, identityResourceGroup, resourceGroup, outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the roles assigned to an identity",
+		Long: `List the Azure RBAC role assignments of a managed identity in the subscription, along with its
+CosmosDB SQL role assignments and Azure Cache for Redis access policy assignments.
+With --resource-group, only assignments in that resource group are listed, which also limits the
+CosmosDB accounts and Redis caches that are checked. Accounts and caches that cannot be checked are
+reported as warnings after the assignments that were found.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("", nil, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			assignmentService := bind.NewAssignmentService(credential, cfg.SubscriptionID)
+
+			assignments, failures, err := assignmentService.ListAssignments(context.Background(), identityName, identityResourceGroup, resourceGroup)
+			if err != nil {
+				return fmt.Errorf("failed to list role assignments: %w", err)
+			}
+			defer printListFailures(failures)
+
+			if outputFormat == "json" {
+				return printJSON(assignments)
+			}
+
+			if len(assignments) == 0 {
+				fmt.Printf("No roles assigned to identity '%s'\n", identityName)
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TYPE\tROLE\tSCOPE")
+			for _, a := range assignments {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", a.Type, a.Role, a.Scope)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to list roles of")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the configured resource group)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Only list assignments in this resource group")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text|json)")
+
+	return cmd
+}
+
+// printListFailures reports the resources whose assignments could not be listed
+func printListFailures(failures []error) {
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", failure)
+	}
+}
+
+// waitFlags are the flags making an assign-role command wait until the granted access has taken effect
+type waitFlags struct {
+	wait        bool
+	waitTimeout time.Duration
+}
+
+func (f *waitFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.wait, "wait", false, "Wait until the granted access has taken effect")
+	cmd.Flags().DurationVar(&f.waitTimeout, "wait-timeout", bind.DefaultWaitTimeout, "Maximum time to wait with --wait")
+}
+
+// timeout returns how long to wait for access to take effect, zero when --wait is not set
+func (f *waitFlags) timeout() time.Duration {
+	if !f.wait {
+		return 0
+	}
+
+	return f.waitTimeout
+}
+
+// writeRuntimeConfig adds the section to the Spin runtime config stored in the Secret, for SpinApps
+// deployed with 'spin azure deploy --runtime-config-secret'
+func writeRuntimeConfig(ctx context.Context, credential azcore.TokenCredential, cfg *config.Config, secretName string, section runtimeconfig.Section) error {
+	runtimeConfigService := runtimeconfig.NewService(credential, cfg.SubscriptionID)
+	if err := runtimeConfigService.AddSection(ctx, secretName, cfg.GetNamespace(), section); err != nil {
+		return fmt.Errorf("failed to write runtime config: %w", err)
+	}
+
+	fmt.Printf("Runtime config written to Secret '%s', deploy with 'spin azure deploy --runtime-config-secret %s'\n", secretName, secretName)
+	return nil
+}
+
+// recordBinding adds the binding to the project file when the command runs inside a Spin app linked with 'spin azure link'
+func recordBinding(binding config.Binding) {
+	projectPath, err := config.RecordBinding(binding)
+	if err != nil {
+		fmt.Printf("Warning: failed to record binding in project file: %v\n", err)
+		return
+	}
+
+	if projectPath != "" {
+		fmt.Printf("Recorded binding in %s\n", projectPath)
+	}
+}
+
+func postgresRole(admin bool) string {
+	if admin {
+		return "admin"
+	}
+
+	return "database role"
+}
+
+// newAzureContext loads the credential and config of a command working on the subscription
+func newAzureContext() (azcore.TokenCredential, *config.Config, error) {
+	credential, err := config.GetAzureCredential()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get Azure credential: %w", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.SubscriptionID == "" {
+		return nil, nil, fmt.Errorf("subscription ID not set, please set it using `spin azure login`")
+	}
+
+	return credential, cfg, nil
+}
+
+// loadBindContext loads the credential and config of an assign-role or revoke-role command and resolves the
+// resource group of the target resource, described by description, and the identity. They fall back to the
+// configured resource group and identity, and the identity to the resource group of the target resource.
+// Commands without a target resource group pass nil.
+func loadBindContext(description string, resourceGroup, identityName, identityResourceGroup *string) (azcore.TokenCredential, *config.Config, error) {
+	credential, cfg, err := newAzureContext()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identityFallback := cfg.ResourceGroup
+	if resourceGroup != nil {
+		if *resourceGroup == "" {
+			*resourceGroup = cfg.ResourceGroup
+		}
+
+		if *resourceGroup == "" {
+			return nil, nil, fmt.Errorf("resource group for %s not set, please set it using --resource-group", description)
+		}
+		identityFallback = *resourceGroup
+	}
+
+	if *identityResourceGroup == "" {
+		*identityResourceGroup = identityFallback
+	}
+
+	if *identityResourceGroup == "" {
+		return nil, nil, fmt.Errorf("resource group for identity not set, please set it using --identity-resource-group")
+	}
+
+	if *identityName == "" {
+		*identityName = cfg.IdentityName
+	}
+
+	if *identityName == "" {
+		return nil, nil, fmt.Errorf("identity name not set, please set it using --identity")
+	}
+
+	return credential, cfg, nil
+}
+
+// newBindService creates a binding service with newService, configured from the config and the wait flags
+func newBindService[S interface{ Configure(bind.Options) }](credential azcore.TokenCredential, cfg *config.Config, flags waitFlags,
+	newService func(azcore.TokenCredential, string) S) S {
+	service := newService(credential, cfg.SubscriptionID)
+	service.Configure(bind.Options{
+		WaitTimeout: flags.timeout(),
+		Namespace:   cfg.GetNamespace(),
+		ProbeImage:  cfg.GetProbeImage(),
+	})
+
+	return service
+}
@@ -22,12 +22,14 @@ func NewClusterCommand() *cobra.Command {
 	cmd.AddCommand(newClusterUseCommand())
 	cmd.AddCommand(newClusterCheckIdentityCommand())
 	cmd.AddCommand(newClusterInstallSpinOperatorCommand())
+	cmd.AddCommand(newClusterConfigureTLSCommand())
+	cmd.AddCommand(newClusterEnableKEDACommand())
 
 	return cmd
 }
 
 func newClusterCreateCommand() *cobra.Command {
-	var name, resourceGroup, location, nodeVMSize string
+	var name, resourceGroup, location, nodeVMSize, attachACR string
 	var nodeCount int
 	var additionalArgs []string
 
@@ -42,6 +44,8 @@ func newClusterCreateCommand() *cobra.Command {
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			customArgs := make(map[string]string)
+			locationSet := cmd.Flags().Changed("location")
+			nodeVMSizeSet := cmd.Flags().Changed("node-vm-size")
 
 			for i := 0; i < len(args); i++ {
 				arg := args[i]
@@ -59,6 +63,7 @@ func newClusterCreateCommand() *cobra.Command {
 				} else if arg == "--location" || arg == "-l" {
 					if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
 						location = args[i+1]
+						locationSet = true
 						i++
 					}
 				} else if arg == "--node-count" {
@@ -72,6 +77,12 @@ func newClusterCreateCommand() *cobra.Command {
 				} else if arg == "--node-vm-size" {
 					if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
 						nodeVMSize = args[i+1]
+						nodeVMSizeSet = true
+						i++
+					}
+				} else if arg == "--attach-acr" {
+					if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+						attachACR = args[i+1]
 						i++
 					}
 				} else if strings.HasPrefix(arg, "--") {
@@ -110,16 +121,16 @@ func newClusterCreateCommand() *cobra.Command {
 				return fmt.Errorf("failed to create AKS service: %w", err)
 			}
 
-			if location == "" {
-				location = "eastus"
+			if !locationSet || location == "" {
+				location = cfg.GetLocation()
 			}
 
 			if nodeCount <= 0 {
 				nodeCount = 1
 			}
 
-			if nodeVMSize == "" {
-				nodeVMSize = "Standard_DS2_v2"
+			if !nodeVMSizeSet || nodeVMSize == "" {
+				nodeVMSize = cfg.GetNodeVMSize()
 			}
 
 			for k, v := range customArgs {
@@ -130,6 +141,10 @@ func newClusterCreateCommand() *cobra.Command {
 				}
 			}
 
+			if attachACR != "" {
+				additionalArgs = append(additionalArgs, "--attach-acr", attachACR)
+			}
+
 			if len(additionalArgs) > 0 {
 				fmt.Println("Additional arguments passed to az aks create:", additionalArgs)
 			}
@@ -153,9 +168,10 @@ func newClusterCreateCommand() *cobra.Command {
 
 	cmd.Flags().StringVar(&name, "name", "", "Name of the AKS cluster (required)")
 	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group for the AKS cluster (required)")
-	cmd.Flags().StringVar(&location, "location", "eastus", "Azure region for the AKS cluster")
+	cmd.Flags().StringVar(&location, "location", config.DefaultLocation, "Azure region for the AKS cluster (defaults to the location config key)")
 	cmd.Flags().IntVar(&nodeCount, "node-count", 1, "Number of nodes in the AKS cluster")
-	cmd.Flags().StringVar(&nodeVMSize, "node-vm-size", "Standard_DS2_v2", "VM size for the AKS cluster nodes")
+	cmd.Flags().StringVar(&nodeVMSize, "node-vm-size", config.DefaultNodeVMSize, "VM size for the AKS cluster nodes (defaults to the node-vm-size config key)")
+	cmd.Flags().StringVar(&attachACR, "attach-acr", "", "Name or resource ID of an Azure Container Registry the cluster can pull images from")
 
 	cmd.Long += `
 
@@ -170,7 +186,7 @@ func newClusterCreateCommand() *cobra.Command {
 }
 
 func newClusterUseCommand() *cobra.Command {
-	var name, resourceGroup string
+	var name, resourceGroup, attachACR string
 	var installSpinOperator bool
 
 	cmd := &cobra.Command{
@@ -212,6 +228,14 @@ func newClusterUseCommand() *cobra.Command {
 
 			fmt.Printf("Now using AKS cluster '%s'\n", name)
 
+			if attachACR != "" {
+				fmt.Printf("Attaching container registry '%s'...\n", attachACR)
+				if err := aksService.AttachACR(ctx, attachACR); err != nil {
+					return fmt.Errorf("failed to attach container registry: %w", err)
+				}
+				fmt.Printf("Cluster '%s' can now pull images from container registry '%s'\n", name, attachACR)
+			}
+
 			if installSpinOperator {
 				fmt.Println("Installing Spin Operator...")
 				if err := aksService.DeploySpinOperator(ctx); err != nil {
@@ -227,6 +251,7 @@ func newClusterUseCommand() *cobra.Command {
 	cmd.Flags().StringVar(&name, "name", "", "Name of the existing AKS cluster (required)")
 	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the existing AKS cluster")
 	cmd.Flags().BoolVar(&installSpinOperator, "install-spin-operator", false, "Install Spin Operator on the cluster after selection")
+	cmd.Flags().StringVar(&attachACR, "attach-acr", "", "Name or resource ID of an Azure Container Registry to grant the cluster pull access to")
 	if err := cmd.MarkFlagRequired("name"); err != nil {
 		panic(fmt.Sprintf("failed to mark flag 'name' as required: %v", err))
 	}
@@ -321,3 +346,103 @@ func newClusterInstallSpinOperatorCommand() *cobra.Command {
 
 	return cmd
 }
+
+func newClusterConfigureTLSCommand() *cobra.Command {
+	var email string
+	var staging bool
+
+	cmd := &cobra.Command{
+		Use:   "configure-tls",
+		Short: "Configure Let's Encrypt certificates for exposed Spin apps",
+		Long:  `Create a Let's Encrypt ClusterIssuer using the cert-manager installed with the Spin Operator, so that 'spin azure deploy --tls' can obtain certificates.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, err := config.GetAzureCredential()
+			if err != nil {
+				return fmt.Errorf("failed to get Azure credential: %w", err)
+			}
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			if cfg.SubscriptionID == "" {
+				return fmt.Errorf("subscription ID not set, please set it using `spin azure login`")
+			}
+
+			aksService, err := aks.NewService(credential, cfg.SubscriptionID)
+			if err != nil {
+				return fmt.Errorf("failed to create AKS service: %w", err)
+			}
+
+			ctx := context.Background()
+			if err := aksService.ConfigureTLS(ctx, email, staging); err != nil {
+				return fmt.Errorf("failed to configure TLS: %w", err)
+			}
+
+			environment := "production"
+			if staging {
+				environment = "staging"
+			}
+			fmt.Printf("ClusterIssuer '%s' now issues certificates from the Let's Encrypt %s environment\n", aks.ClusterIssuerName, environment)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&email, "email", "", "Email address used for the Let's Encrypt account (required)")
+	cmd.Flags().BoolVar(&staging, "staging", false, "Use the Let's Encrypt staging environment")
+	if err := cmd.MarkFlagRequired("email"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'email' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newClusterEnableKEDACommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "enable-keda",
+		Short: "Enable the KEDA add-on on the current cluster",
+		Long:  `Enable the AKS KEDA add-on on the current cluster, so that Spin apps can be scaled on events with 'spin azure deploy --scale-on'.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, err := config.GetAzureCredential()
+			if err != nil {
+				return fmt.Errorf("failed to get Azure credential: %w", err)
+			}
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			if cfg.SubscriptionID == "" {
+				return fmt.Errorf("subscription ID not set, please set it using `spin azure login`")
+			}
+
+			aksService, err := aks.NewService(credential, cfg.SubscriptionID)
+			if err != nil {
+				return fmt.Errorf("failed to create AKS service: %w", err)
+			}
+
+			fmt.Println("Checking if KEDA is enabled on the cluster...")
+			ctx := context.Background()
+			enabled, err := aksService.CheckKEDA(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to check KEDA: %w", err)
+			}
+
+			if enabled {
+				fmt.Println("KEDA is already enabled on the cluster")
+				return nil
+			}
+
+			if err := aksService.EnableKEDA(ctx); err != nil {
+				return fmt.Errorf("failed to enable KEDA: %w", err)
+			}
+
+			fmt.Println("KEDA has been enabled on the cluster")
+			return nil
+		},
+	}
+
+	return cmd
+}
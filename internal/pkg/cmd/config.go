@@ -3,9 +3,14 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
 )
 
 func NewConfigCommand() *cobra.Command {
@@ -16,7 +21,11 @@ func NewConfigCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(newConfigShowCommand())
+	cmd.AddCommand(newConfigGetCommand())
+	cmd.AddCommand(newConfigSetCommand())
+	cmd.AddCommand(newConfigUnsetCommand())
 	cmd.AddCommand(newConfigResetCommand())
+	cmd.AddCommand(newConfigProfileCommand())
 
 	return cmd
 }
@@ -27,26 +36,50 @@ func newConfigShowCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "show",
 		Short: "Show current configuration",
-		Long:  `Display the current configuration settings for the Spin Azure CLI.`,
+		Long: `Display every configuration key, its value and where the value comes from.
+
+Values come from, highest precedence first: command-line flags, SPIN_AZURE_* environment variables,
+the project file written by 'spin azure link', the active profile, and the built-in defaults.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cfg, err := config.LoadConfig()
+			profile, err := config.ActiveProfile()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			settings, err := config.LoadSettings()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			configPath, err := config.ConfigFilePath()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
 			switch outputFormat {
 			case "json":
-				jsonData, err := json.MarshalIndent(cfg, "", "  ")
+				jsonData, err := json.MarshalIndent(map[string]interface{}{
+					"profile":    profile,
+					"configFile": configPath,
+					"precedence": config.Precedence,
+					"settings":   settings,
+				}, "", "  ")
 				if err != nil {
 					return fmt.Errorf("failed to marshal config to JSON: %w", err)
 				}
 				fmt.Println(string(jsonData))
 			default:
-				fmt.Println("Current Configuration:")
-				fmt.Printf("  Subscription ID: %s\n", cfg.SubscriptionID)
-				fmt.Printf("  Resource Group: %s\n", cfg.ResourceGroup)
-				fmt.Printf("  Cluster Name: %s\n", cfg.ClusterName)
-				fmt.Printf("  Identity Name: %s\n", cfg.IdentityName)
+				fmt.Printf("Current Configuration (profile '%s'):\n", profile)
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "  KEY\tVALUE\tSOURCE")
+				for _, setting := range settings {
+					fmt.Fprintf(w, "  %s\t%s\t%s\n", setting.Key, setting.Value, setting.Source)
+				}
+				if err := w.Flush(); err != nil {
+					return err
+				}
+				fmt.Printf("\nConfig file: %s\n", configPath)
+				fmt.Printf("Precedence: %s\n", strings.Join(config.Precedence, " > "))
 			}
 
 			return nil
@@ -58,13 +91,134 @@ func newConfigShowCommand() *cobra.Command {
 	return cmd
 }
 
+func newConfigGetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the value of a configuration key",
+		Long:  `Print the value of a configuration key of the active profile, or its default when it is not set.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := config.LookupKey(args[0])
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			fmt.Println(key.Get(cfg))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newConfigSetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a configuration key",
+		Long: `Validate a value and save it for a configuration key of the active profile.
+
+Keys:
+` + configKeysHelp(),
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := config.LookupKey(args[0])
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			if err := key.Set(cfg, args[1]); err != nil {
+				return err
+			}
+
+			if key.Name == "location" {
+				if err := validateLocation(args[1]); err != nil {
+					return err
+				}
+			}
+
+			if err := config.SaveConfig(cfg); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+
+			fmt.Printf("Set %s to '%s'\n", key.Name, args[1])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newConfigUnsetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Unset a configuration key",
+		Long:  `Clear a configuration key of the active profile, so its default is used again.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := config.LookupKey(args[0])
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			key.Unset(cfg)
+
+			if err := config.SaveConfig(cfg); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+
+			if key.Default != "" {
+				fmt.Printf("Unset %s, the default '%s' is used\n", key.Name, key.Default)
+			} else {
+				fmt.Printf("Unset %s\n", key.Name)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// validateLocation checks that the location is one of the Azure regions available to the signed-in account.
+// The check is skipped with a warning when the regions cannot be listed, for example before 'spin azure login'.
+func validateLocation(location string) error {
+	cmd := exec.Command("az", "account", "list-locations", "--query", "[].name", "--output", "tsv")
+	output, _, err := retry.Output(cmd)
+	if err != nil {
+		fmt.Printf("Warning: could not list Azure regions to validate location '%s': %v\n", location, err)
+		return nil
+	}
+
+	for _, name := range strings.Fields(string(output)) {
+		if name == location {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid value '%s' for location, run 'az account list-locations --query [].name' for the available Azure regions", location)
+}
+
 func newConfigResetCommand() *cobra.Command {
 	var yes bool
 
 	cmd := &cobra.Command{
 		Use:   "reset",
 		Short: "Reset configuration to defaults",
-		Long:  `Reset all configuration settings to their default values.`,
+		Long:  `Reset all configuration settings of the active profile to their default values.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !yes {
 				fmt.Print("Are you sure you want to reset all configuration? This will remove all saved settings. [y/N]: ")
@@ -93,3 +247,142 @@ func newConfigResetCommand() *cobra.Command {
 
 	return cmd
 }
+
+func newConfigProfileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage configuration profiles",
+		Long: `Manage named configuration profiles, each with its own subscription, resource group, cluster and identity.
+
+Commands use the profile selected with --profile, then the SPIN_AZURE_PROFILE environment variable, then the
+current profile set with 'spin azure config profile use'.`,
+	}
+
+	cmd.AddCommand(newConfigProfileCreateCommand())
+	cmd.AddCommand(newConfigProfileUseCommand())
+	cmd.AddCommand(newConfigProfileListCommand())
+	cmd.AddCommand(newConfigProfileDeleteCommand())
+
+	return cmd
+}
+
+func newConfigProfileCreateCommand() *cobra.Command {
+	var from string
+	var use bool
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a configuration profile",
+		Long:  `Create an empty configuration profile, or a copy of an existing profile with --from.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.CreateProfile(args[0], from); err != nil {
+				return fmt.Errorf("failed to create profile: %w", err)
+			}
+			fmt.Printf("Profile '%s' created\n", args[0])
+
+			if use {
+				if err := config.UseProfile(args[0]); err != nil {
+					return fmt.Errorf("failed to switch profile: %w", err)
+				}
+				fmt.Printf("Switched to profile '%s'\n", args[0])
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "Profile to copy the configuration from")
+	cmd.Flags().BoolVar(&use, "use", false, "Make the new profile the current profile")
+
+	return cmd
+}
+
+func newConfigProfileUseCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Switch the current configuration profile",
+		Long:  `Make a profile the current profile, used by commands run without --profile or SPIN_AZURE_PROFILE.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.UseProfile(args[0]); err != nil {
+				return fmt.Errorf("failed to switch profile: %w", err)
+			}
+
+			fmt.Printf("Switched to profile '%s'\n", args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newConfigProfileListCommand() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List configuration profiles",
+		Long:  `List the configuration profiles, the active profile is marked with '*'.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := config.ListProfiles()
+			if err != nil {
+				return fmt.Errorf("failed to list profiles: %w", err)
+			}
+
+			if outputFormat == "json" {
+				return printJSON(profiles)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "CURRENT\tNAME\tSUBSCRIPTION\tRESOURCE GROUP\tCLUSTER\tIDENTITY")
+			for _, p := range profiles {
+				current := ""
+				if p.Current {
+					current = "*"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", current, p.Name, p.SubscriptionID, p.ResourceGroup, p.ClusterName, p.IdentityName)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text|json)")
+
+	return cmd
+}
+
+func newConfigProfileDeleteCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a configuration profile",
+		Long:  `Delete a configuration profile. The current profile cannot be deleted.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.DeleteProfile(args[0]); err != nil {
+				return fmt.Errorf("failed to delete profile: %w", err)
+			}
+
+			fmt.Printf("Profile '%s' deleted\n", args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// configKeysHelp describes every configuration key and its default
+func configKeysHelp() string {
+	var help strings.Builder
+	w := tabwriter.NewWriter(&help, 0, 0, 2, ' ', 0)
+	for _, key := range config.Keys {
+		description := key.Description
+		if key.Default != "" {
+			description += fmt.Sprintf(" (default %s)", key.Default)
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", key.Name, description)
+	}
+	w.Flush()
+
+	return strings.TrimSuffix(help.String(), "\n")
+}
@@ -3,15 +3,22 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/aks"
 	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
 	"github.com/spinframework/spin-plugin-azure/internal/pkg/deploy"
 )
 
 // NewDeployCommand creates a new deploy command
 func NewDeployCommand() *cobra.Command {
-	var from string
+	var from, host, autoscaleRange, scaleOn, serviceBusNamespace, runtimeConfigSecret string
+	var expose, tls bool
+	var cpuTarget int
+	var variableArgs []string
 
 	cmd := &cobra.Command{
 		Use:   "deploy",
@@ -40,11 +47,131 @@ func NewDeployCommand() *cobra.Command {
 				return fmt.Errorf("no identity configured, please set it using the 'identity create' command")
 			}
 
+			if host != "" && !expose {
+				return fmt.Errorf("--host can only be used together with --expose")
+			}
+
+			if tls && host == "" {
+				return fmt.Errorf("--tls requires --expose and --host")
+			}
+
+			if host != "" {
+				if err := deploy.ValidateHost(host); err != nil {
+					return err
+				}
+			}
+
+			var autoscale *deploy.Autoscale
+			if autoscaleRange != "" {
+				autoscale, err = newAutoscale(autoscaleRange, cpuTarget, scaleOn, serviceBusNamespace)
+				if err != nil {
+					return err
+				}
+			} else if cpuTarget != 0 || scaleOn != "" {
+				return fmt.Errorf("--cpu-target and --scale-on require --autoscale")
+			}
+
+			var variables []deploy.Variable
+			usesKeyVault := false
+			for _, arg := range variableArgs {
+				variable, err := deploy.ParseVariable(arg)
+				if err != nil {
+					return err
+				}
+				usesKeyVault = usesKeyVault || variable.IsKeyVaultReference()
+				variables = append(variables, variable)
+			}
+
+			if usesKeyVault && cfg.TenantID == "" {
+				return fmt.Errorf("tenant ID not set, please set it using `spin azure login`")
+			}
+
+			ctx := context.Background()
+
+			aksService, err := aks.NewService(credential, cfg.SubscriptionID)
+			if err != nil {
+				return fmt.Errorf("failed to create AKS service: %w", err)
+			}
+
+			var identityClientID string
+			if usesKeyVault || scaleOn != "" {
+				identityClientID, err = aksService.GetIdentityClientID(cfg.IdentityName, cfg.ResourceGroup)
+				if err != nil {
+					return fmt.Errorf("failed to get client ID of identity '%s': %w", cfg.IdentityName, err)
+				}
+			}
+
+			if usesKeyVault {
+				enabled, err := aksService.CheckKeyVaultSecretsProvider(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to check Key Vault secrets provider: %w", err)
+				}
+
+				if !enabled {
+					fmt.Println("Key Vault secrets provider add-on is not enabled, enabling it now...")
+					if err := aksService.EnableKeyVaultSecretsProvider(ctx); err != nil {
+						return fmt.Errorf("failed to enable Key Vault secrets provider: %w", err)
+					}
+				}
+			}
+
+			if autoscale != nil && scaleOn != "" {
+				enabled, err := aksService.CheckKEDA(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to check KEDA: %w", err)
+				}
+
+				if !enabled {
+					return fmt.Errorf("KEDA is not enabled on the cluster, enable it using 'spin azure cluster enable-keda'")
+				}
+
+				if err := aksService.FederateKEDAOperator(ctx, cfg.IdentityName); err != nil {
+					return fmt.Errorf("failed to federate KEDA operator with identity '%s': %w", cfg.IdentityName, err)
+				}
+
+				hasRole, err := aksService.CheckKEDAServiceBusRole(ctx, cfg.IdentityName, autoscale.ServiceBusNamespace, autoscale.ServiceBusQueue)
+				if err != nil {
+					return fmt.Errorf("failed to check the Service Bus role of identity '%s': %w", cfg.IdentityName, err)
+				}
+
+				// Not an error, since the role may also be held through a group
+				if !hasRole {
+					fmt.Fprintf(os.Stderr, "Warning: identity '%s' has no '%s' role on queue '%s', which KEDA needs to read the queue length. "+
+						"Grant it using 'spin azure assign-role servicebus --namespace %s --queue %s --role owner'\n",
+						cfg.IdentityName, aks.KEDAServiceBusRole, autoscale.ServiceBusQueue, autoscale.ServiceBusNamespace, autoscale.ServiceBusQueue)
+				}
+			}
+
+			if expose {
+				enabled, err := aksService.CheckAppRouting(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to check application routing: %w", err)
+				}
+
+				if !enabled {
+					fmt.Println("Application routing add-on is not enabled, enabling it now...")
+					if err := aksService.EnableAppRouting(ctx); err != nil {
+						return fmt.Errorf("failed to enable application routing: %w", err)
+					}
+				}
+			}
+
 			deployService := deploy.NewService(credential, cfg.SubscriptionID)
+			opts := deploy.Options{
+				Expose:              expose,
+				Host:                host,
+				Autoscale:           autoscale,
+				Variables:           variables,
+				RuntimeConfigSecret: runtimeConfigSecret,
+				IdentityClientID:    identityClientID,
+				TenantID:            cfg.TenantID,
+			}
+			if tls {
+				opts.ClusterIssuer = aks.ClusterIssuerName
+			}
 
 			fmt.Printf("Deploying Spin application from '%s' using identity '%s'...\n", from, cfg.IdentityName)
-			ctx := context.Background()
-			if err := deployService.Deploy(ctx, from, cfg.IdentityName); err != nil {
+			if err := deployService.Deploy(ctx, from, cfg.IdentityName, opts); err != nil {
 				return fmt.Errorf("failed to deploy Spin application: %w", err)
 			}
 
@@ -54,9 +181,88 @@ func NewDeployCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&from, "from", "", "Path to the SpinApp YAML file (required)")
+	cmd.Flags().BoolVar(&expose, "expose", false, "Expose the SpinApp publicly through an Ingress using the AKS application routing add-on")
+	cmd.Flags().StringVar(&host, "host", "", "Host name to route to the SpinApp when using --expose")
+	cmd.Flags().BoolVar(&tls, "tls", false, "Obtain a Let's Encrypt certificate for --host (requires 'spin azure cluster configure-tls')")
+	cmd.Flags().StringArrayVar(&variableArgs, "variable", nil, "Spin variable to set, in the form key=value or key=@keyvault:<vault>/<secret> (repeatable)")
+	cmd.Flags().StringVar(&runtimeConfigSecret, "runtime-config-secret", "", "Kubernetes Secret holding the Spin runtime config, as written by 'spin azure assign-role --runtime-config-secret'")
+	cmd.Flags().StringVar(&autoscaleRange, "autoscale", "", "Enable autoscaling between min and max replicas, in the form min:max")
+	cmd.Flags().IntVar(&cpuTarget, "cpu-target", 0, "Average CPU utilization percentage to scale on (defaults to 80 unless --scale-on is set)")
+	cmd.Flags().StringVar(&scaleOn, "scale-on", "", "Scale with KEDA on an event source, in the form servicebus-queue=<queue> (the identity needs Azure Service Bus Data Owner on the queue)")
+	cmd.Flags().StringVar(&serviceBusNamespace, "servicebus-namespace", "", "Service Bus namespace of the queue used with --scale-on")
 	if err := cmd.MarkFlagRequired("from"); err != nil {
 		panic(fmt.Sprintf("failed to mark flag 'from' as required: %v", err))
 	}
 
 	return cmd
 }
+
+// newAutoscale builds the autoscaling settings from the deploy flags
+func newAutoscale(autoscaleRange string, cpuTarget int, scaleOn, serviceBusNamespace string) (*deploy.Autoscale, error) {
+	minReplicas, maxReplicas, err := parseAutoscaleRange(autoscaleRange)
+	if err != nil {
+		return nil, err
+	}
+
+	if cpuTarget < 0 {
+		return nil, fmt.Errorf("--cpu-target must be a positive percentage")
+	}
+
+	autoscale := &deploy.Autoscale{
+		MinReplicas: minReplicas,
+		MaxReplicas: maxReplicas,
+		CPUTarget:   cpuTarget,
+	}
+
+	if scaleOn == "" {
+		if minReplicas < 1 {
+			return nil, fmt.Errorf("--autoscale minimum must be at least 1 unless --scale-on is set")
+		}
+		if autoscale.CPUTarget == 0 {
+			autoscale.CPUTarget = 80
+		}
+		return autoscale, nil
+	}
+
+	source, value, found := strings.Cut(scaleOn, "=")
+	if !found || value == "" {
+		return nil, fmt.Errorf("invalid --scale-on '%s', expected the form servicebus-queue=<queue>", scaleOn)
+	}
+
+	switch source {
+	case "servicebus-queue":
+		if serviceBusNamespace == "" {
+			return nil, fmt.Errorf("--servicebus-namespace is required when scaling on a Service Bus queue")
+		}
+		autoscale.ServiceBusQueue = value
+		autoscale.ServiceBusNamespace = serviceBusNamespace
+	default:
+		return nil, fmt.Errorf("unsupported --scale-on source '%s', supported sources: servicebus-queue", source)
+	}
+
+	return autoscale, nil
+}
+
+// parseAutoscaleRange parses a replica range in the form min:max
+func parseAutoscaleRange(value string) (int, int, error) {
+	minValue, maxValue, found := strings.Cut(value, ":")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid --autoscale '%s', expected the form min:max", value)
+	}
+
+	minReplicas, err := strconv.Atoi(minValue)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --autoscale minimum '%s': %w", minValue, err)
+	}
+
+	maxReplicas, err := strconv.Atoi(maxValue)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --autoscale maximum '%s': %w", maxValue, err)
+	}
+
+	if minReplicas < 0 || maxReplicas < 1 || minReplicas > maxReplicas {
+		return 0, 0, fmt.Errorf("invalid --autoscale '%s', expected 0 <= min <= max and max >= 1", value)
+	}
+
+	return minReplicas, maxReplicas, nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import "testing"
+
+func TestParseAutoscaleRange(t *testing.T) {
+	minReplicas, maxReplicas, err := parseAutoscaleRange("2:10")
+	if err != nil {
+		t.Fatalf("Failed to parse autoscale range: %v", err)
+	}
+
+	if minReplicas != 2 || maxReplicas != 10 {
+		t.Errorf("Expected range 2:10, got %d:%d", minReplicas, maxReplicas)
+	}
+
+	for _, value := range []string{"10", "a:2", "1:b", "5:2", "-1:2", "0:0"} {
+		if _, _, err := parseAutoscaleRange(value); err == nil {
+			t.Errorf("Expected '%s' to be rejected", value)
+		}
+	}
+}
+
+func TestNewAutoscale(t *testing.T) {
+	autoscale, err := newAutoscale("1:5", 0, "", "")
+	if err != nil {
+		t.Fatalf("Failed to create autoscale settings: %v", err)
+	}
+
+	if autoscale.CPUTarget != 80 {
+		t.Errorf("Expected default CPU target to be 80, got %d", autoscale.CPUTarget)
+	}
+
+	if _, err := newAutoscale("0:5", 0, "", ""); err == nil {
+		t.Error("Expected scaling to zero without --scale-on to be rejected")
+	}
+
+	autoscale, err = newAutoscale("0:5", 0, "servicebus-queue=orders", "my-servicebus")
+	if err != nil {
+		t.Fatalf("Failed to create autoscale settings: %v", err)
+	}
+
+	if autoscale.ServiceBusQueue != "orders" || autoscale.ServiceBusNamespace != "my-servicebus" {
+		t.Errorf("Expected Service Bus queue 'orders' in 'my-servicebus', got '%s' in '%s'", autoscale.ServiceBusQueue, autoscale.ServiceBusNamespace)
+	}
+
+	if autoscale.CPUTarget != 0 {
+		t.Errorf("Expected no CPU target when scaling on events, got %d", autoscale.CPUTarget)
+	}
+
+	if _, err := newAutoscale("0:5", 0, "servicebus-queue=orders", ""); err == nil {
+		t.Error("Expected missing --servicebus-namespace to be rejected")
+	}
+
+	if _, err := newAutoscale("0:5", 0, "kafka-topic=orders", ""); err == nil {
+		t.Error("Expected unsupported --scale-on source to be rejected")
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+)
+
+func NewLinkCommand() *cobra.Command {
+	var dir, clusterName, resourceGroup, identityName, namespace string
+
+	cmd := &cobra.Command{
+		Use:   "link",
+		Short: "Link a Spin app directory to a cluster and identity",
+		Long: `Write a project file next to spin.toml recording the cluster, resource group, identity and namespace
+the Spin app uses. Commands run inside the app directory use these values instead of the global configuration,
+and assign-role commands record the bindings they create in the project file.
+
+Values that are not set with flags are taken from the current configuration.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, ok := config.FindProjectDir(dir)
+			if !ok {
+				return fmt.Errorf("spin.toml not found in '%s' or its parent directories, run 'spin azure link' in a Spin app directory", dir)
+			}
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			project, err := config.ReadProject(projectDir)
+			if err != nil {
+				return err
+			}
+			if project == nil {
+				project = &config.Project{}
+			}
+
+			values := []struct {
+				key   string
+				value string
+				field *string
+			}{
+				{"cluster-name", orValue(clusterName, cfg.ClusterName), &project.ClusterName},
+				{"resource-group", orValue(resourceGroup, cfg.ResourceGroup), &project.ResourceGroup},
+				{"identity-name", orValue(identityName, cfg.IdentityName), &project.IdentityName},
+				{"namespace", orValue(namespace, cfg.GetNamespace()), &project.Namespace},
+			}
+			for _, v := range values {
+				if v.value == "" {
+					continue
+				}
+				key, err := config.LookupKey(v.key)
+				if err != nil {
+					return err
+				}
+				if err := key.Validate(v.value); err != nil {
+					return err
+				}
+				*v.field = v.value
+			}
+
+			if project.ClusterName == "" || project.ResourceGroup == "" {
+				return fmt.Errorf("no cluster is currently selected, use --cluster and --resource-group or 'spin azure cluster use' first")
+			}
+
+			projectPath, err := config.SaveProject(projectDir, project)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Linked '%s' to cluster '%s' (in resource group '%s')", projectDir, project.ClusterName, project.ResourceGroup)
+			if project.IdentityName != "" {
+				fmt.Printf(" with identity '%s'", project.IdentityName)
+			}
+			fmt.Printf(" in namespace '%s'\n", project.Namespace)
+			fmt.Printf("Project configuration written to %s\n", projectPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", ".", "Directory of the Spin app, or one of its subdirectories")
+	cmd.Flags().StringVar(&clusterName, "cluster", "", "Name of the AKS cluster the app is deployed to (defaults to the current cluster)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the cluster and identity (defaults to the current resource group)")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity the app runs as (defaults to the current identity)")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Kubernetes namespace of the app (defaults to the namespace config key)")
+
+	return cmd
+}
+
+func orValue(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
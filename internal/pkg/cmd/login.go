@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
 	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
 )
 
@@ -34,13 +35,13 @@ func NewLoginCommand() *cobra.Command {
 
 				output, err := subCmd.CombinedOutput()
 				if err != nil {
-					return fmt.Errorf("failed to set subscription: %w\nOutput: %s", err, string(output))
+					return fmt.Errorf("failed to set subscription: %w", clierror.New(err, output))
 				}
 			} else {
 				subCmd := exec.Command("az", "account", "show", "--query", "id", "--output", "tsv")
 				output, err := subCmd.CombinedOutput()
 				if err != nil {
-					return fmt.Errorf("failed to get current subscription: %w\nOutput: %s", err, string(output))
+					return fmt.Errorf("failed to get current subscription: %w", clierror.New(err, output))
 				}
 				subscriptionID = strings.TrimSpace(string(output))
 				fmt.Printf("Using subscription: %s\n", subscriptionID)
@@ -52,7 +53,7 @@ func NewLoginCommand() *cobra.Command {
 				tenantCmd := exec.Command("az", "account", "show", "--query", "tenantId", "--output", "tsv")
 				output, err := tenantCmd.CombinedOutput()
 				if err != nil {
-					return fmt.Errorf("failed to get current tenant: %w\nOutput: %s", err, string(output))
+					return fmt.Errorf("failed to get current tenant: %w", clierror.New(err, output))
 				}
 				tenantID = strings.TrimSpace(string(output))
 				fmt.Printf("Using tenant ID: %s\n", tenantID)
@@ -97,7 +98,7 @@ func NewLogoutCommand() *cobra.Command {
 
 			output, err := logoutCmd.CombinedOutput()
 			if err != nil {
-				return fmt.Errorf("failed to log out from Azure: %w\nOutput: %s", err, string(output))
+				return fmt.Errorf("failed to log out from Azure: %w", clierror.New(err, output))
 			}
 
 			cfg, err := config.LoadConfig()
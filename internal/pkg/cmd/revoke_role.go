@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/aks"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/bind"
+)
+
+func NewRevokeRoleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "revoke-role",
+		Short: "Revoke roles assigned to managed identities",
+		Long:  `Remove the role assignments, access policies and data-plane role assignments made by 'spin azure assign-role'.`,
+	}
+
+	cmd.AddCommand(newRevokeCosmosDBCommand())
+	cmd.AddCommand(newRevokeRedisCommand())
+	cmd.AddCommand(newRevokePostgresCommand())
+	cmd.AddCommand(newRevokeACRCommand())
+	cmd.AddCommand(newRevokeResourceCommand("keyvault", "name", "Key Vault"))
+	cmd.AddCommand(newRevokeResourceCommand("storage", "account", "storage account"))
+	cmd.AddCommand(newRevokeResourceCommand("servicebus", "namespace", "Service Bus namespace"))
+	cmd.AddCommand(newRevokeResourceCommand("eventhubs", "namespace", "Event Hubs namespace"))
+	cmd.AddCommand(newRevokeResourceCommand("openai", "account", "Azure OpenAI account"))
+	cmd.AddCommand(newRevokeGenericCommand())
+
+	return cmd
+}
+
+// newAssignmentService loads the config and the identity to revoke roles of, falling back to the
+// configured identity and to the resource group of the target resource, described by description
+func newAssignmentService(description string, resourceGroup, identityName, identityResourceGroup *string) (*bind.AssignmentService, error) {
+	credential, cfg, err := loadBindContext(description, resourceGroup, identityName, identityResourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return bind.NewAssignmentService(credential, cfg.SubscriptionID), nil
+}
+
+func printRevoked(count int, identityName, target string) {
+	if count == 0 {
+		fmt.Printf("No roles of identity '%s' found on %s\n", identityName, target)
+		return
+	}
+
+	fmt.Printf("Successfully revoked %d role(s) of identity '%s' on %s\n", count, identityName, target)
+}
+
+func newRevokeCosmosDBCommand() *cobra.Command {
+	var name, resourceGroup, identityName, identityResourceGroup string
+
+	cmd := &cobra.Command{
+		Use:   "cosmosdb",
+		Short: "Revoke CosmosDB roles",
+		Long:  `Delete the CosmosDB SQL role assignments of a managed identity on a CosmosDB account.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			assignmentService, err := newAssignmentService("CosmosDB", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			count, err := assignmentService.RevokeCosmosDB(context.Background(), name, resourceGroup, identityName, identityResourceGroup)
+			if err != nil {
+				return fmt.Errorf("failed to revoke CosmosDB roles: %w", err)
+			}
+
+			printRevoked(count, identityName, fmt.Sprintf("CosmosDB '%s'", name))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Name of the CosmosDB account (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the CosmosDB account")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to revoke roles of")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the CosmosDB resource group if not specified)")
+	if err := cmd.MarkFlagRequired("name"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'name' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newRevokeRedisCommand() *cobra.Command {
+	var name, resourceGroup, identityName, identityResourceGroup string
+
+	cmd := &cobra.Command{
+		Use:   "redis",
+		Short: "Revoke Azure Cache for Redis access policies",
+		Long:  `Delete the data access policy assignments of a managed identity on an Azure Cache for Redis.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			assignmentService, err := newAssignmentService("Redis cache", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			count, err := assignmentService.RevokeRedis(context.Background(), name, resourceGroup, identityName, identityResourceGroup)
+			if err != nil {
+				return fmt.Errorf("failed to revoke Redis access policies: %w", err)
+			}
+
+			printRevoked(count, identityName, fmt.Sprintf("Redis cache '%s'", name))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Name of the Azure Cache for Redis (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the Redis cache")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to revoke access policies of")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the Redis cache resource group if not specified)")
+	if err := cmd.MarkFlagRequired("name"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'name' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newRevokePostgresCommand() *cobra.Command {
+	var name, resourceGroup, database, identityName, identityResourceGroup string
+	var admin bool
+
+	cmd := &cobra.Command{
+		Use:   "postgres",
+		Short: "Revoke PostgreSQL access",
+		Long: `Remove the access of a managed identity to an Azure Database for PostgreSQL Flexible Server.
+The database role of the identity is dropped by the signed-in user, who must be a Microsoft Entra administrator
+of the server. The role must not own objects in the database. With --admin, the identity is removed from the
+Microsoft Entra administrators instead.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			assignmentService, err := newAssignmentService("PostgreSQL server", &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			count, err := assignmentService.RevokePostgres(context.Background(), name, resourceGroup, database, admin, identityName, identityResourceGroup)
+			if err != nil {
+				return fmt.Errorf("failed to revoke PostgreSQL access: %w", err)
+			}
+
+			printRevoked(count, identityName, fmt.Sprintf("PostgreSQL server '%s'", name))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Name of the PostgreSQL Flexible Server (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the PostgreSQL server")
+	cmd.Flags().StringVar(&database, "database", "postgres", "Database the identity was granted access to")
+	cmd.Flags().BoolVar(&admin, "admin", false, "Remove the identity from the Microsoft Entra administrators instead of dropping its database role")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to revoke access of")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the PostgreSQL server resource group if not specified)")
+	if err := cmd.MarkFlagRequired("name"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'name' as required: %v", err))
+	}
+
+	return cmd
+}
+
+func newRevokeACRCommand() *cobra.Command {
+	var registry, resourceGroup string
+
+	cmd := &cobra.Command{
+		Use:   "acr",
+		Short: "Stop the current cluster from pulling images from Azure Container Registry",
+		Long:  `Delete the AcrPull role assignment of the kubelet identity of the current AKS cluster on an Azure Container Registry.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := newAzureContext()
+			if err != nil {
+				return err
+			}
+
+			if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+				return fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' first")
+			}
+
+			if resourceGroup == "" {
+				resourceGroup = cfg.ResourceGroup
+			}
+
+			aksService, err := aks.NewService(credential, cfg.SubscriptionID)
+			if err != nil {
+				return fmt.Errorf("failed to create AKS service: %w", err)
+			}
+
+			kubeletObjectID, err := aksService.GetKubeletIdentityObjectID(cfg.ClusterName, cfg.ResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			assignmentService := bind.NewAssignmentService(credential, cfg.SubscriptionID)
+			count, err := assignmentService.RevokeACR(context.Background(), registry, resourceGroup, kubeletObjectID)
+			if err != nil {
+				return fmt.Errorf("failed to revoke AcrPull on container registry: %w", err)
+			}
+
+			if count == 0 {
+				fmt.Printf("Cluster '%s' has no AcrPull role on container registry '%s'\n", cfg.ClusterName, registry)
+				return nil
+			}
+
+			fmt.Printf("Cluster '%s' can no longer pull images from container registry '%s'\n", cfg.ClusterName, registry)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&registry, "registry", "", "Name of the Azure Container Registry (required)")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group of the container registry (defaults to the resource group of the current cluster)")
+	if err := cmd.MarkFlagRequired("registry"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'registry' as required: %v", err))
+	}
+
+	return cmd
+}
+
+// newRevokeResourceCommand creates the revoke command of a service whose access is granted with Azure RBAC
+// on a resource identified by nameFlag, matching the flags of the corresponding assign-role command
+func newRevokeResourceCommand(serviceName, nameFlag, description string) *cobra.Command {
+	var name, resourceGroup, role, identityName, identityResourceGroup string
+
+	cmd := &cobra.Command{
+		Use:   serviceName,
+		Short: fmt.Sprintf("Revoke roles on a %s", description),
+		Long: fmt.Sprintf(`Delete the Azure RBAC role assignments of a managed identity on a %s and on anything inside it.
+Only the built-in roles granted by 'spin azure assign-role %s' are deleted, or the role set with --role,
+so other roles of the identity are kept.`, description, serviceName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			assignmentService, err := newAssignmentService(description, &resourceGroup, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			count, err := assignmentService.RevokeResource(context.Background(), serviceName, name, resourceGroup, role, identityName, identityResourceGroup)
+			if err != nil {
+				return fmt.Errorf("failed to revoke roles on %s: %w", description, err)
+			}
+
+			printRevoked(count, identityName, fmt.Sprintf("%s '%s'", description, name))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, nameFlag, "", fmt.Sprintf("Name of the %s (required)", description))
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", fmt.Sprintf("Resource group of the %s", description))
+	cmd.Flags().StringVar(&role, "role", "", "Only revoke this role, e.g. 'Storage Blob Data Reader', or a custom role granted with assign-role")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to revoke roles of")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", fmt.Sprintf("Resource group of the managed identity (defaults to the %s resource group if not specified)", description))
+	if err := cmd.MarkFlagRequired(nameFlag); err != nil {
+		panic(fmt.Sprintf("failed to mark flag '%s' as required: %v", nameFlag, err))
+	}
+
+	return cmd
+}
+
+func newRevokeGenericCommand() *cobra.Command {
+	var scope, role, identityName, identityResourceGroup string
+
+	cmd := &cobra.Command{
+		Use:   "generic",
+		Short: "Revoke roles at any scope",
+		Long: `Delete the Azure RBAC role assignments of a role held by a managed identity at the scope of any subscription,
+resource group or resource, and below it. The role is required, so that other roles of the identity are kept.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			credential, cfg, err := loadBindContext("", nil, &identityName, &identityResourceGroup)
+			if err != nil {
+				return err
+			}
+
+			assignmentService := bind.NewAssignmentService(credential, cfg.SubscriptionID)
+
+			count, err := assignmentService.RevokeScope(context.Background(), scope, role, identityName, identityResourceGroup)
+			if err != nil {
+				return fmt.Errorf("failed to revoke roles: %w", err)
+			}
+
+			printRevoked(count, identityName, fmt.Sprintf("scope '%s'", scope))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&scope, "scope", "", "ID of the subscription, resource group or resource to revoke roles at (required)")
+	cmd.Flags().StringVar(&role, "role", "", "Role to revoke (required)")
+	cmd.Flags().StringVar(&identityName, "identity", "", "Name of the identity to revoke roles of")
+	cmd.Flags().StringVar(&identityResourceGroup, "identity-resource-group", "", "Resource group of the managed identity (defaults to the configured resource group)")
+	if err := cmd.MarkFlagRequired("scope"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'scope' as required: %v", err))
+	}
+	if err := cmd.MarkFlagRequired("role"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag 'role' as required: %v", err))
+	}
+
+	return cmd
+}
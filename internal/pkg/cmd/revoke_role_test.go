@@ -0,0 +1,35 @@
+package cmd
+
+import "testing"
+
+func TestNewRevokeRoleCommand(t *testing.T) {
+	cmd := NewRevokeRoleCommand()
+	if cmd.Use != "revoke-role" {
+		t.Errorf("Expected command use to be 'revoke-role', got '%s'", cmd.Use)
+	}
+
+	flags := map[string]string{
+		"cosmosdb":   "name",
+		"redis":      "name",
+		"postgres":   "name",
+		"acr":        "registry",
+		"keyvault":   "name",
+		"storage":    "account",
+		"servicebus": "namespace",
+		"eventhubs":  "namespace",
+		"openai":     "account",
+		"generic":    "scope",
+	}
+
+	for name, flag := range flags {
+		subcommand := findSubcommand(cmd.Commands(), name)
+		if subcommand == nil {
+			t.Errorf("Expected to find '%s' subcommand", name)
+			continue
+		}
+
+		if subcommand.Flags().Lookup(flag) == nil {
+			t.Errorf("Expected '%s' subcommand to have '%s' flag", name, flag)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
 )
 
 func NewRootCommand() *cobra.Command {
@@ -20,6 +22,9 @@ and deploy Spin apps to them.`,
   # Use an existing AKS cluster
   spin azure cluster use --name existing-cluster --resource-group existing-rg
 
+  # Allow the current cluster to pull images from Azure Container Registry
+  spin azure assign-role acr --registry myregistry
+
   # Create a new identity
   spin azure identity create --name my-custom-identity
 
@@ -32,23 +37,50 @@ and deploy Spin apps to them.`,
   # Assign Azure CosmosDB role to an identity
   spin azure assign-role cosmosdb --name my-cosmos --resource-group my-rg
 
+  # Grant an identity access to Key Vault secrets
+  spin azure assign-role keyvault --name my-vault --resource-group my-rg
+
+  # List the roles assigned to the configured identity
+  spin azure assign-role list
+
+  # Revoke the roles of an identity on a storage account
+  spin azure revoke-role storage --account mystorage --resource-group my-rg
+
+  # Use a cluster and identity for the Spin app in the current directory only
+  spin azure link --cluster app-cluster --resource-group app-rg --identity app-identity
+
   # Deploy a Spin application
   spin azure deploy --from path/to/spinapp.yaml
+
+  # List deployed Spin applications
+  spin azure app list
  
   # Output the config
   spin azure config show
 
+  # Keep the configuration of a production environment in its own profile
+  spin azure config profile create prod
+  spin azure cluster use --name prod-cluster --resource-group prod-rg --profile prod
+
   # Reset the config
   spin azure config reset -y`,
 	}
 
+	cmd.PersistentFlags().StringVar(&config.Profile, "profile", "",
+		"Configuration profile to use, overrides SPIN_AZURE_PROFILE and the current profile")
+	cmd.PersistentFlags().IntVar(&retry.MaxRetries, "max-retries", retry.DefaultMaxRetries,
+		"Number of times to retry az, kubectl and helm commands that fail with a transient error (0 disables retries)")
+
 	cmd.AddCommand(NewLoginCommand())
 	cmd.AddCommand(NewLogoutCommand())
 	cmd.AddCommand(NewClusterCommand())
 	cmd.AddCommand(NewIdentityCommand())
 	cmd.AddCommand(NewAssignRoleCommand())
+	cmd.AddCommand(NewRevokeRoleCommand())
 	cmd.AddCommand(NewDeployCommand())
+	cmd.AddCommand(NewAppCommand())
 	cmd.AddCommand(NewConfigCommand())
+	cmd.AddCommand(NewLinkCommand())
 
 	return cmd
 }
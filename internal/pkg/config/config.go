@@ -1,82 +1,279 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 )
 
 type Config struct {
-	SubscriptionID string `json:"subscriptionId"`
-	TenantID       string `json:"tenantId"`
-	ResourceGroup  string `json:"resourceGroup"`
-	ClusterName    string `json:"clusterName"`
-	IdentityName   string `json:"identityName"`
+	SubscriptionID      string `json:"subscriptionId"`
+	TenantID            string `json:"tenantId"`
+	ResourceGroup       string `json:"resourceGroup"`
+	ClusterName         string `json:"clusterName"`
+	IdentityName        string `json:"identityName"`
+	Location            string `json:"location,omitempty"`
+	NodeVMSize          string `json:"nodeVmSize,omitempty"`
+	Namespace           string `json:"namespace,omitempty"`
+	SpinOperatorVersion string `json:"spinOperatorVersion,omitempty"`
+	CertManagerVersion  string `json:"certManagerVersion,omitempty"`
+	ShimVersion         string `json:"shimVersion,omitempty"`
+	ProbeImage          string `json:"probeImage,omitempty"`
 }
 
+// ConfigDirEnvVar sets the directory of the config file
+const ConfigDirEnvVar = "SPIN_AZURE_CONFIG_DIR"
+
+// loadedConfig is what SaveConfig needs to know about a configuration returned by LoadConfig
+type loadedConfig struct {
+	// profile holds the values stored in the profile when the configuration was loaded
+	profile Config
+	// overrides holds the values the project file and environment put over the profile
+	overrides map[string]string
+	// set holds the keys changed with Key.Set or Key.Unset, which are saved even when they equal an override
+	set map[string]bool
+}
+
+var (
+	// loaded holds the state of each configuration returned by LoadConfig
+	loaded   = map[*Config]loadedConfig{}
+	loadedMu sync.Mutex
+)
+
+// GetConfigDir returns the directory of the config file, creating it if needed. It is
+// SPIN_AZURE_CONFIG_DIR when set, otherwise spin-azure in the XDG config directory, which is
+// XDG_CONFIG_HOME or ~/.config. An existing ~/.spin-azure of an earlier version is kept in use
+// as long as the XDG directory does not exist.
 func GetConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := configDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get user home directory: %w", err)
+		return "", err
 	}
 
-	configDir := filepath.Join(homeDir, ".spin-azure")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(configDir, 0700); err != nil {
 		return "", fmt.Errorf("failed to create config directory: %w", err)
 	}
 
 	return configDir, nil
 }
 
-// LoadConfig loads the configuration from disk
+func configDir() (string, error) {
+	if dir := os.Getenv(ConfigDirEnvVar); dir != "" {
+		return dir, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" {
+		xdgConfigHome = filepath.Join(homeDir, ".config")
+	}
+
+	xdgDir := filepath.Join(xdgConfigHome, "spin-azure")
+	if _, err := os.Stat(xdgDir); err == nil {
+		return xdgDir, nil
+	}
+
+	legacyDir := filepath.Join(homeDir, ".spin-azure")
+	if _, err := os.Stat(legacyDir); err == nil {
+		return legacyDir, nil
+	}
+
+	return xdgDir, nil
+}
+
+// LoadConfig loads the configuration of the active profile from disk. Inside a Spin app linked with
+// 'spin azure link', the values of the project file take precedence, and SPIN_AZURE_* environment
+// variables take precedence over both.
 func LoadConfig() (*Config, error) {
-	configDir, err := GetConfigDir()
+	config, profile, _, err := resolveConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	configPath := filepath.Join(configDir, "config.json")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		defaultConfig := &Config{}
-		return defaultConfig, nil
+	overrides := map[string]string{}
+	for _, key := range Keys {
+		if value := *key.field(config); value != *key.field(profile) {
+			overrides[key.Name] = value
+		}
 	}
 
-	data, err := os.ReadFile(configPath)
+	loadedMu.Lock()
+	loaded[config] = loadedConfig{profile: *profile, overrides: overrides, set: map[string]bool{}}
+	loadedMu.Unlock()
+
+	return config, nil
+}
+
+// resolveConfig merges the active profile, the project file and the environment, and returns
+// the values stored in the profile and where each key that is set comes from
+func resolveConfig() (*Config, *Config, map[string]string, error) {
+	file, err := readConfigFile()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, nil, nil, err
 	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	name, err := file.activeProfile()
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	return &config, nil
+	profile := &Config{}
+	if stored, ok := file.Profiles[name]; ok {
+		profile = stored
+	}
+	merged := *profile
+	config := &merged
+
+	sources := map[string]string{}
+	for _, key := range Keys {
+		if *key.field(config) != "" {
+			sources[key.Name] = fmt.Sprintf("profile '%s'", name)
+		}
+	}
+
+	project, projectPath, err := LoadProject()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if project != nil {
+		for _, key := range project.apply(config) {
+			sources[key] = fmt.Sprintf("project %s", projectPath)
+		}
+	}
+
+	for _, key := range Keys {
+		if key.EnvVar == "" {
+			continue
+		}
+		value := os.Getenv(key.EnvVar)
+		if value == "" {
+			continue
+		}
+		if err := key.Validate(value); err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w", key.EnvVar, err)
+		}
+		*key.field(config) = value
+		sources[key.Name] = fmt.Sprintf("env %s", key.EnvVar)
+	}
+
+	return config, profile, sources, nil
 }
 
-// SaveConfig saves the configuration to disk
+// SaveConfig saves the configuration of the active profile to disk. For a configuration returned by
+// LoadConfig, only the values that differ from the profile as it was loaded are written, so commands
+// running at the same time do not undo each other's changes. Values coming from the project file or the
+// environment are not written unless they were changed with Key.Set or Key.Unset. A warning is printed
+// for written values that the project file or environment still override.
 func SaveConfig(config *Config) error {
-	configDir, err := GetConfigDir()
+	project, projectPath, err := LoadProject()
 	if err != nil {
 		return err
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	loadedMu.Lock()
+	state, fromLoad := loaded[config]
+	loadedMu.Unlock()
+
+	var profileName string
+	var saved Config
+	var written []Key
+	err = updateConfigFile(func(file *configFile) error {
+		name, err := file.activeProfile()
+		if err != nil {
+			return err
+		}
+		profileName = name
+
+		stored, ok := file.Profiles[name]
+		if !ok {
+			stored = &Config{}
+		}
+
+		saved = *config
+		written = nil
+		if fromLoad {
+			saved = *stored
+			for _, key := range Keys {
+				value := *key.field(config)
+				override, overridden := state.overrides[key.Name]
+				switch {
+				case state.set[key.Name]:
+					// changed explicitly, saved even when it equals an override
+				case overridden && value == override:
+					// still the value of the project file or environment
+					continue
+				case value == *key.field(&state.profile):
+					// unchanged, which keeps what other commands saved meanwhile
+					continue
+				}
+				*key.field(&saved) = value
+				written = append(written, key)
+			}
+		} else {
+			if project != nil {
+				project.restore(&saved, stored)
+			}
+			for _, key := range Keys {
+				if *key.field(&saved) != *key.field(stored) {
+					written = append(written, key)
+				}
+			}
+		}
+
+		file.Profiles[name] = &saved
+		if file.CurrentProfile == "" {
+			file.CurrentProfile = name
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to serialize config: %w", err)
+		return err
 	}
 
-	configPath := filepath.Join(configDir, "config.json")
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	for _, key := range written {
+		if shadow := shadowedBy(key, project, projectPath); shadow != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s was saved to profile '%s', but %s overrides it\n", key.Name, profileName, shadow)
+		}
 	}
 
+	loadedMu.Lock()
+	loaded[config] = loadedConfig{profile: saved, overrides: state.overrides, set: map[string]bool{}}
+	loadedMu.Unlock()
+
 	return nil
 }
 
+// markSet records that the key of a loaded configuration was changed explicitly
+func markSet(config *Config, name string) {
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+
+	if state, ok := loaded[config]; ok {
+		state.set[name] = true
+	}
+}
+
+// shadowedBy returns the environment variable or project file overriding the key, if any
+func shadowedBy(key Key, project *Project, projectPath string) string {
+	if key.EnvVar != "" && os.Getenv(key.EnvVar) != "" {
+		return key.EnvVar
+	}
+	if project != nil {
+		if field, ok := projectKeys[key.Name]; ok && *field(project) != "" {
+			return "project file " + projectPath
+		}
+	}
+
+	return ""
+}
+
 // GetAzureCredential returns an Azure credential for authentication
 func GetAzureCredential() (*azidentity.DefaultAzureCredential, error) {
 	credential, err := azidentity.NewDefaultAzureCredential(nil)
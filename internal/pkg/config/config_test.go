@@ -0,0 +1,320 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupHome(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ProfileEnvVar, "")
+	t.Setenv(ConfigDirEnvVar, "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	for _, key := range Keys {
+		if key.EnvVar != "" {
+			t.Setenv(key.EnvVar, "")
+		}
+	}
+	Profile = ""
+	t.Cleanup(func() { Profile = "" })
+	chdir(t, t.TempDir())
+
+	return home
+}
+
+func chdir(t *testing.T, dir string) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(workingDir) })
+}
+
+func TestMigrateConfig(t *testing.T) {
+	home := setupHome(t)
+
+	configDir := filepath.Join(home, ".spin-azure")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `{"subscriptionId": "sub", "resourceGroup": "my-rg", "clusterName": "my-cluster", "identityName": "my-identity"}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	if cfg.SubscriptionID != "sub" || cfg.ClusterName != "my-cluster" {
+		t.Errorf("Expected the legacy config to be loaded, got %+v", cfg)
+	}
+
+	profiles, err := ListProfiles()
+	if err != nil {
+		t.Fatalf("ListProfiles() failed: %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].Name != DefaultProfile || !profiles[0].Current {
+		t.Errorf("Expected the legacy config to be migrated to the current '%s' profile, got %+v", DefaultProfile, profiles)
+	}
+}
+
+func TestListProfilesWithoutConfig(t *testing.T) {
+	setupHome(t)
+
+	profiles, err := ListProfiles()
+	if err != nil {
+		t.Fatalf("ListProfiles() failed: %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].Name != DefaultProfile || !profiles[0].Current {
+		t.Errorf("Expected the active '%s' profile to be listed, got %+v", DefaultProfile, profiles)
+	}
+
+	if err := CreateProfile("prod", ""); err != nil {
+		t.Fatalf("CreateProfile() failed: %v", err)
+	}
+	if err := UseProfile("prod"); err != nil {
+		t.Fatalf("UseProfile() failed: %v", err)
+	}
+	if err := UseProfile(DefaultProfile); err != nil {
+		t.Errorf("Expected to switch back to the unsaved '%s' profile, got %v", DefaultProfile, err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	setupHome(t)
+
+	if err := SaveConfig(&Config{SubscriptionID: "dev-sub"}); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	if err := CreateProfile("prod", ""); err != nil {
+		t.Fatalf("CreateProfile() failed: %v", err)
+	}
+	if err := CreateProfile("prod", ""); err == nil {
+		t.Error("Expected creating an existing profile to fail")
+	}
+	if err := CreateProfile("not valid", ""); err == nil {
+		t.Error("Expected an invalid profile name to be rejected")
+	}
+
+	Profile = "prod"
+	if err := SaveConfig(&Config{SubscriptionID: "prod-sub"}); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	Profile = ""
+	t.Setenv(ProfileEnvVar, "prod")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	if cfg.SubscriptionID != "prod-sub" {
+		t.Errorf("Expected %s to select the profile, got subscription '%s'", ProfileEnvVar, cfg.SubscriptionID)
+	}
+
+	t.Setenv(ProfileEnvVar, "")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	if cfg.SubscriptionID != "dev-sub" {
+		t.Errorf("Expected the current profile to be used, got subscription '%s'", cfg.SubscriptionID)
+	}
+
+	if err := UseProfile("prod"); err != nil {
+		t.Fatalf("UseProfile() failed: %v", err)
+	}
+	if err := DeleteProfile("prod"); err == nil {
+		t.Error("Expected deleting the current profile to fail")
+	}
+	if err := DeleteProfile(DefaultProfile); err != nil {
+		t.Errorf("DeleteProfile() failed: %v", err)
+	}
+
+	Profile = "missing"
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected selecting a missing profile to fail")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		valid bool
+	}{
+		{"subscription-id", "00000000-0000-0000-0000-000000000000", true},
+		{"subscription-id", "my-subscription", false},
+		{"resource-group", "my-rg", true},
+		{"resource-group", "my-rg.", false},
+		{"location", "westeurope", true},
+		{"location", "West Europe", false},
+		{"node-vm-size", "Standard_D4s_v5", true},
+		{"node-vm-size", "D4s_v5", false},
+		{"namespace", "spin-apps", true},
+		{"namespace", "Spin_Apps", false},
+		{"spin-operator-version", "0.5.0", true},
+		{"cert-manager-version", "v1.16.2", true},
+		{"shim-version", "latest", false},
+	}
+
+	for _, test := range tests {
+		key, err := LookupKey(test.key)
+		if err != nil {
+			t.Fatalf("LookupKey(%q) failed: %v", test.key, err)
+		}
+		if err := key.Validate(test.value); (err == nil) != test.valid {
+			t.Errorf("Validate(%q) for %s = %v, expected valid: %v", test.value, test.key, err, test.valid)
+		}
+	}
+
+	if _, err := LookupKey("cluster"); err == nil {
+		t.Error("Expected an unknown key to be rejected")
+	}
+
+	key, _ := LookupKey("namespace")
+	cfg := &Config{}
+	if got := key.Get(cfg); got != DefaultNamespace {
+		t.Errorf("Expected the default namespace, got '%s'", got)
+	}
+	if err := key.Set(cfg, "apps"); err != nil || cfg.GetNamespace() != "apps" {
+		t.Errorf("Expected the namespace to be set, got '%s' (%v)", cfg.Namespace, err)
+	}
+	key.Unset(cfg)
+	if cfg.Namespace != "" {
+		t.Errorf("Expected the namespace to be unset, got '%s'", cfg.Namespace)
+	}
+}
+
+func TestProject(t *testing.T) {
+	setupHome(t)
+
+	if err := SaveConfig(&Config{SubscriptionID: "sub", ClusterName: "global-cluster", ResourceGroup: "global-rg"}); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	appDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(appDir, "spin.toml"), []byte("spin_manifest_version = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveProject(appDir, &Project{ClusterName: "app-cluster", Namespace: "apps"}); err != nil {
+		t.Fatalf("SaveProject() failed: %v", err)
+	}
+
+	srcDir := filepath.Join(appDir, "src")
+	if err := os.Mkdir(srcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, srcDir)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	if cfg.ClusterName != "app-cluster" || cfg.GetNamespace() != "apps" || cfg.ResourceGroup != "global-rg" {
+		t.Errorf("Expected the project to override the global config, got %+v", cfg)
+	}
+
+	cfg.IdentityName = "app-identity"
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	file, err := readConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	global := file.Profiles[DefaultProfile]
+	if global.ClusterName != "global-cluster" || global.Namespace != "" || global.IdentityName != "app-identity" {
+		t.Errorf("Expected project values to stay out of the profile, got %+v", global)
+	}
+
+	binding := Binding{Service: "keyvault", Name: "my-vault", ResourceGroup: "global-rg", Role: "secrets-reader", Identity: "app-identity"}
+	if _, err := RecordBinding(binding); err != nil {
+		t.Fatalf("RecordBinding() failed: %v", err)
+	}
+	binding.Role = "secrets-officer"
+	if _, err := RecordBinding(binding); err != nil {
+		t.Fatalf("RecordBinding() failed: %v", err)
+	}
+
+	project, err := ReadProject(appDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Bindings) != 1 || project.Bindings[0].Role != "secrets-officer" {
+		t.Errorf("Expected the binding to be replaced, got %+v", project.Bindings)
+	}
+}
+
+func TestConfigDir(t *testing.T) {
+	home := setupHome(t)
+
+	dir, err := GetConfigDir()
+	if err != nil || dir != filepath.Join(home, ".config", "spin-azure") {
+		t.Errorf("Expected ~/.config/spin-azure, got '%s' (%v)", dir, err)
+	}
+
+	xdgConfigHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdgConfigHome)
+	if dir, _ := GetConfigDir(); dir != filepath.Join(xdgConfigHome, "spin-azure") {
+		t.Errorf("Expected the XDG config directory, got '%s'", dir)
+	}
+
+	legacyHome := t.TempDir()
+	t.Setenv("HOME", legacyHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if err := os.Mkdir(filepath.Join(legacyHome, ".spin-azure"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if dir, _ := GetConfigDir(); dir != filepath.Join(legacyHome, ".spin-azure") {
+		t.Errorf("Expected the existing ~/.spin-azure to be kept, got '%s'", dir)
+	}
+
+	configDir := t.TempDir()
+	t.Setenv(ConfigDirEnvVar, configDir)
+	if dir, _ := GetConfigDir(); dir != configDir {
+		t.Errorf("Expected %s to take precedence, got '%s'", ConfigDirEnvVar, dir)
+	}
+}
+
+func TestEnvironmentOverrides(t *testing.T) {
+	setupHome(t)
+
+	if err := SaveConfig(&Config{ClusterName: "stored-cluster", ResourceGroup: "stored-rg"}); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	t.Setenv("SPIN_AZURE_CLUSTER", "ci-cluster")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	if cfg.ClusterName != "ci-cluster" || cfg.ResourceGroup != "stored-rg" {
+		t.Errorf("Expected SPIN_AZURE_CLUSTER to override the stored cluster, got %+v", cfg)
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() failed: %v", err)
+	}
+	for _, setting := range settings {
+		if setting.Key == "cluster-name" && setting.Source != "env SPIN_AZURE_CLUSTER" {
+			t.Errorf("Expected the cluster to come from the environment, got '%s'", setting.Source)
+		}
+		if setting.Key == "resource-group" && setting.Source != "profile 'default'" {
+			t.Errorf("Expected the resource group to come from the profile, got '%s'", setting.Source)
+		}
+	}
+
+	t.Setenv("SPIN_AZURE_NAMESPACE", "Not_Valid")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected an invalid SPIN_AZURE_NAMESPACE to be rejected")
+	}
+}
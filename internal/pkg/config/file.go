@@ -0,0 +1,259 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	configFileName = "config.json"
+
+	// lockTimeout is how long to wait for another process to finish writing the config file
+	lockTimeout       = 10 * time.Second
+	lockRetryInterval = 100 * time.Millisecond
+)
+
+// migrations upgrade the config file from the version at their index plus one to the next version.
+// Add a migration and the file is upgraded the next time it is read.
+var migrations = []func(document map[string]json.RawMessage) error{
+	migrateToProfiles,
+}
+
+// errCorruptConfig is returned for a config file that is not valid JSON
+var errCorruptConfig = errors.New("config file is corrupt")
+
+// currentVersion is the version of the config file written by this version of the plugin
+var currentVersion = len(migrations) + 1
+
+// configFile is the content of config.json, holding the configuration of every profile
+type configFile struct {
+	Version        int                `json:"version"`
+	CurrentProfile string             `json:"currentProfile"`
+	Profiles       map[string]*Config `json:"profiles"`
+}
+
+// ConfigFilePath returns the path of the config file
+func ConfigFilePath() (string, error) {
+	return configFilePath()
+}
+
+func configFilePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, configFileName), nil
+}
+
+// readConfigFile reads config.json. A file written by an older version is migrated and a corrupt
+// file is backed up, and the result is saved so this happens only once.
+func readConfigFile() (*configFile, error) {
+	configPath, err := configFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, upToDate, err := parseConfigFile(configPath)
+	if err == nil && upToDate {
+		return file, nil
+	}
+	if err != nil && !errors.Is(err, errCorruptConfig) {
+		return nil, err
+	}
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "Migrated config file %s to version %d\n", configPath, currentVersion)
+	}
+
+	file = &configFile{}
+	if err := updateConfigFile(func(updated *configFile) error {
+		*file = *updated
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// updateConfigFile reads config.json, applies update and writes the result, holding the config
+// lock so concurrent commands do not overwrite each other's changes
+func updateConfigFile(update func(*configFile) error) error {
+	configPath, err := configFilePath()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(configPath + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, _, err := parseConfigFile(configPath)
+	if errors.Is(err, errCorruptConfig) {
+		file, err = backupConfigFile(configPath, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := update(file); err != nil {
+		return err
+	}
+
+	return writeConfigFile(configPath, file)
+}
+
+// parseConfigFile reads and migrates config.json, and reports whether the file on disk is up to date
+func parseConfigFile(configPath string) (*configFile, bool, error) {
+	file := &configFile{Version: currentVersion, Profiles: map[string]*Config{}}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return file, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", errCorruptConfig, err)
+	}
+
+	version, err := documentVersion(document)
+	if err != nil {
+		return nil, false, err
+	}
+	if version > currentVersion {
+		return nil, false, fmt.Errorf("config file %s has version %d, which requires a newer version of the plugin", configPath, version)
+	}
+
+	for v := version; v < currentVersion; v++ {
+		if err := migrations[v-1](document); err != nil {
+			return nil, false, fmt.Errorf("failed to migrate config file to version %d: %w", v+1, err)
+		}
+	}
+	document["version"], _ = json.Marshal(currentVersion)
+
+	data, err = json.Marshal(document)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to migrate config file: %w", err)
+	}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", errCorruptConfig, err)
+	}
+
+	if file.Profiles == nil {
+		file.Profiles = map[string]*Config{}
+	}
+	for name, config := range file.Profiles {
+		if config == nil {
+			file.Profiles[name] = &Config{}
+		}
+	}
+
+	return file, version == currentVersion, nil
+}
+
+// documentVersion returns the version of a config file. Files written before the version field
+// was added are version 1 without profiles, and version 2 with profiles.
+func documentVersion(document map[string]json.RawMessage) (int, error) {
+	if raw, ok := document["version"]; ok {
+		var version int
+		if err := json.Unmarshal(raw, &version); err != nil || version < 1 {
+			return 0, fmt.Errorf("failed to parse config file: invalid version %s", raw)
+		}
+		return version, nil
+	}
+
+	if _, ok := document["profiles"]; ok {
+		return 2, nil
+	}
+
+	return 1, nil
+}
+
+// migrateToProfiles moves the settings of a config file written before profiles existed to the default profile
+func migrateToProfiles(document map[string]json.RawMessage) error {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	for key := range document {
+		delete(document, key)
+	}
+
+	if document["profiles"], err = json.Marshal(map[string]*Config{DefaultProfile: &config}); err != nil {
+		return err
+	}
+	document["currentProfile"], err = json.Marshal(DefaultProfile)
+	return err
+}
+
+// backupConfigFile moves a config file that cannot be read aside, so commands can start over
+// with an empty configuration without losing the original
+func backupConfigFile(configPath string, parseErr error) (*configFile, error) {
+	backupPath := fmt.Sprintf("%s.corrupt-%s", configPath, time.Now().Format("20060102-150405"))
+	if err := os.Rename(configPath, backupPath); err != nil {
+		return nil, fmt.Errorf("%w, and failed to back it up: %v", parseErr, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %v\nThe config file was backed up to %s and a new one was created\n", parseErr, backupPath)
+
+	return &configFile{Version: currentVersion, Profiles: map[string]*Config{}}, nil
+}
+
+func writeConfigFile(configPath string, file *configFile) error {
+	file.Version = currentVersion
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %w", err)
+	}
+
+	if err := writeFileAtomic(configPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes the data to a temporary file and renames it over path, so readers never
+// see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if err := tempFile.Chmod(perm); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path)
+}
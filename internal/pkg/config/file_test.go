@@ -0,0 +1,149 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCorruptConfigIsBackedUp(t *testing.T) {
+	home := setupHome(t)
+
+	configPath := filepath.Join(home, ".spin-azure", configFileName)
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte(`{"profiles": {`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected a corrupt config to be replaced, got %v", err)
+	}
+	if *cfg != (Config{}) {
+		t.Errorf("Expected an empty config, got %+v", cfg)
+	}
+
+	backups, _ := filepath.Glob(configPath + ".corrupt-*")
+	if len(backups) != 1 {
+		t.Fatalf("Expected one backup of the corrupt config, got %v", backups)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != `{"profiles": {` {
+		t.Errorf("Expected the backup to hold the corrupt config, got %q", data)
+	}
+}
+
+func TestConfigFileVersion(t *testing.T) {
+	home := setupHome(t)
+
+	if err := SaveConfig(&Config{SubscriptionID: "sub"}); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	configPath := filepath.Join(home, ".config", "spin-azure", configFileName)
+	info, err := os.Stat(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected config file permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file configFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != currentVersion {
+		t.Errorf("Expected version %d, got %d", currentVersion, file.Version)
+	}
+
+	newer := `{"version": 99, "profiles": {}}`
+	if err := os.WriteFile(configPath, []byte(newer), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected a config file from a newer version to be rejected")
+	}
+}
+
+func TestSaveConfigKeepsConcurrentChanges(t *testing.T) {
+	setupHome(t)
+
+	first, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.ClusterName = "my-cluster"
+	if err := SaveConfig(first); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	second.IdentityName = "my-identity"
+	if err := SaveConfig(second); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClusterName != "my-cluster" || cfg.IdentityName != "my-identity" {
+		t.Errorf("Expected both changes to be saved, got %+v", cfg)
+	}
+}
+
+func TestSaveConfigWithOverrides(t *testing.T) {
+	setupHome(t)
+
+	if err := SaveConfig(&Config{ClusterName: "stored-cluster"}); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	t.Setenv("SPIN_AZURE_CLUSTER", "ci-cluster")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.IdentityName = "my-identity"
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	file, err := readConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile := file.Profiles[DefaultProfile]; profile.ClusterName != "stored-cluster" || profile.IdentityName != "my-identity" {
+		t.Errorf("Expected only the identity to be saved, got %+v", profile)
+	}
+
+	key, err := LookupKey("cluster-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := key.Set(cfg, "ci-cluster"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	if file, err = readConfigFile(); err != nil {
+		t.Fatal(err)
+	}
+	if profile := file.Profiles[DefaultProfile]; profile.ClusterName != "ci-cluster" {
+		t.Errorf("Expected a value set explicitly to be saved even when it equals the override, got %+v", profile)
+	}
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultLocation            = "eastus"
+	DefaultNodeVMSize          = "Standard_DS2_v2"
+	DefaultNamespace           = "default"
+	DefaultSpinOperatorVersion = "0.4.0"
+	DefaultCertManagerVersion  = "v1.14.3"
+	DefaultShimVersion         = "v0.18.0"
+	DefaultProbeImage          = "curlimages/curl:8.11.1"
+)
+
+var (
+	guidPattern          = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	resourceGroupPattern = regexp.MustCompile(`^[\w\-.()]{0,89}[\w\-()]$`)
+	clusterNamePattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_-]{0,61}[A-Za-z0-9])?$`)
+	identityNamePattern  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{2,127}$`)
+	locationPattern      = regexp.MustCompile(`^[a-z][a-z0-9]+$`)
+	vmSizePattern        = regexp.MustCompile(`^(Standard|Basic)_[A-Za-z0-9_]+$`)
+	namespacePattern     = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	versionPattern       = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?$`)
+	imagePattern         = regexp.MustCompile(`^[a-z0-9][a-z0-9._/:-]*(@sha256:[0-9a-f]{64})?$`)
+)
+
+// Key is a configuration setting that can be read and written with 'spin azure config get|set|unset'
+type Key struct {
+	Name        string
+	Description string
+	// Default is used when the key is not set
+	Default string
+	// Pattern validates values and Format describes it in errors
+	Pattern *regexp.Regexp
+	Format  string
+	// EnvVar overrides the stored value when it is set
+	EnvVar string
+
+	field func(*Config) *string
+}
+
+// Keys are the configuration keys in the order they are shown
+var Keys = []Key{
+	{Name: "subscription-id", Description: "Azure subscription", Pattern: guidPattern, Format: "a GUID",
+		EnvVar: "SPIN_AZURE_SUBSCRIPTION",
+		field:  func(c *Config) *string { return &c.SubscriptionID }},
+	{Name: "tenant-id", Description: "Microsoft Entra tenant", Pattern: guidPattern, Format: "a GUID",
+		field: func(c *Config) *string { return &c.TenantID }},
+	{Name: "resource-group", Description: "Resource group of the cluster and identities", Pattern: resourceGroupPattern,
+		Format: "up to 90 letters, digits, '_', '-', '.', '(' and ')', not ending with '.'",
+		EnvVar: "SPIN_AZURE_RESOURCE_GROUP",
+		field:  func(c *Config) *string { return &c.ResourceGroup }},
+	{Name: "cluster-name", Description: "AKS cluster apps are deployed to", Pattern: clusterNamePattern,
+		Format: "up to 63 letters, digits, '_' and '-', starting and ending with a letter or digit",
+		EnvVar: "SPIN_AZURE_CLUSTER",
+		field:  func(c *Config) *string { return &c.ClusterName }},
+	{Name: "identity-name", Description: "Managed identity apps run as", Pattern: identityNamePattern,
+		Format: "3 to 128 letters, digits, '_' and '-', starting with a letter or digit",
+		EnvVar: "SPIN_AZURE_IDENTITY",
+		field:  func(c *Config) *string { return &c.IdentityName }},
+	{Name: "location", Description: "Azure region of new clusters", Default: DefaultLocation, Pattern: locationPattern,
+		Format: "an Azure region name such as 'eastus' or 'westeurope'",
+		field:  func(c *Config) *string { return &c.Location }},
+	{Name: "node-vm-size", Description: "VM size of the nodes of new clusters", Default: DefaultNodeVMSize, Pattern: vmSizePattern,
+		Format: "an Azure VM size such as 'Standard_DS2_v2'",
+		field:  func(c *Config) *string { return &c.NodeVMSize }},
+	{Name: "namespace", Description: "Kubernetes namespace of apps and their service accounts", Default: DefaultNamespace, Pattern: namespacePattern,
+		Format: "up to 63 lowercase letters, digits and '-', starting and ending with a letter or digit",
+		EnvVar: "SPIN_AZURE_NAMESPACE",
+		field:  func(c *Config) *string { return &c.Namespace }},
+	{Name: "spin-operator-version", Description: "Spin Operator version installed on clusters", Default: DefaultSpinOperatorVersion, Pattern: versionPattern,
+		Format: "a version such as '0.4.0'",
+		field:  func(c *Config) *string { return &c.SpinOperatorVersion }},
+	{Name: "cert-manager-version", Description: "cert-manager version installed on clusters", Default: DefaultCertManagerVersion, Pattern: versionPattern,
+		Format: "a version such as 'v1.14.3'",
+		field:  func(c *Config) *string { return &c.CertManagerVersion }},
+	{Name: "shim-version", Description: "containerd-shim-spin version installed on cluster nodes", Default: DefaultShimVersion, Pattern: versionPattern,
+		Format: "a version such as 'v0.18.0'",
+		field:  func(c *Config) *string { return &c.ShimVersion }},
+	{Name: "probe-image", Description: "Image of the pod checking data-plane access with --wait, it needs sh and curl", Default: DefaultProbeImage, Pattern: imagePattern,
+		Format: "an image reference such as 'myregistry.azurecr.io/curl:8.11.1'",
+		field:  func(c *Config) *string { return &c.ProbeImage }},
+}
+
+// Precedence lists where values come from, highest first
+var Precedence = []string{
+	"command-line flags",
+	"SPIN_AZURE_* environment variables",
+	"project file (" + ProjectFileName + ")",
+	"profile",
+	"default",
+}
+
+// Setting is the effective value of a key and where it comes from
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// LookupKey returns the configuration key with the given name
+func LookupKey(name string) (*Key, error) {
+	for i := range Keys {
+		if Keys[i].Name == name {
+			return &Keys[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown config key '%s', valid keys are: %s", name, strings.Join(KeyNames(), ", "))
+}
+
+// KeyNames returns the names of the configuration keys
+func KeyNames() []string {
+	names := make([]string, 0, len(Keys))
+	for _, key := range Keys {
+		names = append(names, key.Name)
+	}
+
+	return names
+}
+
+// Validate checks that the value has the format of the key
+func (k *Key) Validate(value string) error {
+	if !k.Pattern.MatchString(value) {
+		return fmt.Errorf("invalid value '%s' for %s, expected %s", value, k.Name, k.Format)
+	}
+
+	return nil
+}
+
+// Get returns the value of the key in the configuration, or its default when it is not set
+func (k *Key) Get(c *Config) string {
+	if value := *k.field(c); value != "" {
+		return value
+	}
+
+	return k.Default
+}
+
+// Set validates the value and stores it in the configuration
+func (k *Key) Set(c *Config, value string) error {
+	if err := k.Validate(value); err != nil {
+		return err
+	}
+
+	*k.field(c) = value
+	markSet(c, k.Name)
+	return nil
+}
+
+// Unset clears the key, so its default is used again
+func (k *Key) Unset(c *Config) {
+	*k.field(c) = ""
+	markSet(c, k.Name)
+}
+
+// LoadSettings returns the effective value of every key and where it comes from
+func LoadSettings() ([]Setting, error) {
+	config, _, sources, err := resolveConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]Setting, 0, len(Keys))
+	for _, key := range Keys {
+		setting := Setting{Key: key.Name, Value: key.Get(config), Source: sources[key.Name]}
+		if setting.Source == "" {
+			setting.Source = "default"
+			if key.Default == "" {
+				setting.Source = "not set"
+			}
+		}
+		settings = append(settings, setting)
+	}
+
+	return settings, nil
+}
+
+func (c *Config) GetLocation() string {
+	return orDefault(c.Location, DefaultLocation)
+}
+
+func (c *Config) GetNodeVMSize() string {
+	return orDefault(c.NodeVMSize, DefaultNodeVMSize)
+}
+
+func (c *Config) GetNamespace() string {
+	return orDefault(c.Namespace, DefaultNamespace)
+}
+
+func (c *Config) GetSpinOperatorVersion() string {
+	return orDefault(c.SpinOperatorVersion, DefaultSpinOperatorVersion)
+}
+
+func (c *Config) GetCertManagerVersion() string {
+	return orDefault(c.CertManagerVersion, DefaultCertManagerVersion)
+}
+
+func (c *Config) GetShimVersion() string {
+	return orDefault(c.ShimVersion, DefaultShimVersion)
+}
+
+func (c *Config) GetProbeImage() string {
+	return orDefault(c.ProbeImage, DefaultProbeImage)
+}
+
+func orDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,35 @@
+//go:build !linux && !darwin
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockFile takes an exclusive lock by creating path, waiting up to lockTimeout for other processes
+// to remove it. A lock file older than lockTimeout was left behind by a process that did not exit
+// cleanly and is removed.
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock config file: %w", err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockTimeout {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("config file is locked by another spin azure command, try again when it has finished")
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+const (
+	// DefaultProfile is the profile used when none is selected, and the profile a configuration
+	// written before profiles existed is migrated to
+	DefaultProfile = "default"
+
+	// ProfileEnvVar selects the profile when --profile is not set
+	ProfileEnvVar = "SPIN_AZURE_PROFILE"
+)
+
+// Profile is the profile selected with --profile for the current command, it takes precedence
+// over SPIN_AZURE_PROFILE and the current profile in the config file
+var Profile string
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ProfileInfo describes a profile for listing
+type ProfileInfo struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+	Config
+}
+
+// ListProfiles returns the profiles sorted by name, the active one is marked as current. The default
+// profile is listed while it is active even before anything is saved to it.
+func ListProfiles() ([]ProfileInfo, error) {
+	file, err := readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := file.activeProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(file.Profiles)+1)
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	if _, ok := file.Profiles[active]; !ok {
+		names = append(names, active)
+	}
+	sort.Strings(names)
+
+	profiles := make([]ProfileInfo, 0, len(names))
+	for _, name := range names {
+		profile := ProfileInfo{
+			Name:    name,
+			Current: name == active,
+		}
+		if config, ok := file.Profiles[name]; ok {
+			profile.Config = *config
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
+
+// ActiveProfile returns the name of the profile commands read and write their configuration in
+func ActiveProfile() (string, error) {
+	file, err := readConfigFile()
+	if err != nil {
+		return "", err
+	}
+
+	return file.activeProfile()
+}
+
+// CreateProfile adds an empty profile, or a copy of an existing profile when from is set
+func CreateProfile(name, from string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s', use letters, digits, '.', '_' and '-'", name)
+	}
+
+	return updateConfigFile(func(file *configFile) error {
+		if _, ok := file.Profiles[name]; ok {
+			return fmt.Errorf("profile '%s' already exists", name)
+		}
+
+		config := &Config{}
+		if from != "" {
+			source, ok := file.Profiles[from]
+			if !ok {
+				return fmt.Errorf("profile '%s' does not exist", from)
+			}
+			copied := *source
+			config = &copied
+		}
+
+		file.Profiles[name] = config
+		return nil
+	})
+}
+
+// UseProfile makes the profile the current one for commands run without --profile
+func UseProfile(name string) error {
+	return updateConfigFile(func(file *configFile) error {
+		if _, ok := file.Profiles[name]; !ok && name != DefaultProfile {
+			return fmt.Errorf("profile '%s' does not exist", name)
+		}
+
+		file.CurrentProfile = name
+		return nil
+	})
+}
+
+// DeleteProfile removes a profile, the current profile cannot be deleted
+func DeleteProfile(name string) error {
+	return updateConfigFile(func(file *configFile) error {
+		if _, ok := file.Profiles[name]; !ok {
+			return fmt.Errorf("profile '%s' does not exist", name)
+		}
+
+		if name == file.currentProfile() {
+			return fmt.Errorf("profile '%s' is the current profile, switch to another profile before deleting it", name)
+		}
+
+		delete(file.Profiles, name)
+		return nil
+	})
+}
+
+// activeProfile returns the profile selected with --profile, SPIN_AZURE_PROFILE or the current profile in the file
+func (f *configFile) activeProfile() (string, error) {
+	name := Profile
+	if name == "" {
+		name = os.Getenv(ProfileEnvVar)
+	}
+	if name == "" {
+		return f.currentProfile(), nil
+	}
+
+	if _, ok := f.Profiles[name]; !ok && name != DefaultProfile {
+		return "", fmt.Errorf("profile '%s' does not exist, create it with 'spin azure config profile create %s'", name, name)
+	}
+
+	return name, nil
+}
+
+func (f *configFile) currentProfile() string {
+	if f.CurrentProfile == "" {
+		return DefaultProfile
+	}
+
+	return f.CurrentProfile
+}
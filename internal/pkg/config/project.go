@@ -0,0 +1,167 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// ProjectFileName is the project file written by 'spin azure link' next to spin.toml
+	ProjectFileName = ".spin-azure.json"
+
+	spinManifestName = "spin.toml"
+)
+
+// Project is the configuration of a Spin app directory linked with 'spin azure link'. Its values
+// take precedence over the global configuration for commands run inside the directory.
+type Project struct {
+	ClusterName   string    `json:"clusterName,omitempty"`
+	ResourceGroup string    `json:"resourceGroup,omitempty"`
+	IdentityName  string    `json:"identityName,omitempty"`
+	Namespace     string    `json:"namespace,omitempty"`
+	Bindings      []Binding `json:"bindings,omitempty"`
+}
+
+// Binding records access granted to the app's identity with 'spin azure assign-role'
+type Binding struct {
+	Service       string `json:"service"`
+	Name          string `json:"name"`
+	ResourceGroup string `json:"resourceGroup,omitempty"`
+	Role          string `json:"role,omitempty"`
+	Identity      string `json:"identity,omitempty"`
+}
+
+// projectKeys are the configuration keys a project can set
+var projectKeys = map[string]func(*Project) *string{
+	"cluster-name":   func(p *Project) *string { return &p.ClusterName },
+	"resource-group": func(p *Project) *string { return &p.ResourceGroup },
+	"identity-name":  func(p *Project) *string { return &p.IdentityName },
+	"namespace":      func(p *Project) *string { return &p.Namespace },
+}
+
+// FindProjectDir returns the closest directory containing spin.toml, starting from dir and going up
+func FindProjectDir(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, spinManifestName)); err == nil {
+			return dir, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads the project file of the Spin app containing the working directory.
+// It returns nil without an error when the working directory is not inside a linked app.
+func LoadProject() (*Project, string, error) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	projectDir, ok := FindProjectDir(workingDir)
+	if !ok {
+		return nil, "", nil
+	}
+
+	project, err := ReadProject(projectDir)
+	if err != nil || project == nil {
+		return nil, "", err
+	}
+
+	return project, filepath.Join(projectDir, ProjectFileName), nil
+}
+
+// ReadProject reads the project file in projectDir, it returns nil without an error when there is none
+func ReadProject(projectDir string) (*Project, error) {
+	projectPath := filepath.Join(projectDir, ProjectFileName)
+	data, err := os.ReadFile(projectPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
+	}
+
+	var project Project
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse project file %s: %w", projectPath, err)
+	}
+
+	return &project, nil
+}
+
+// SaveProject writes the project file next to the spin.toml in projectDir
+func SaveProject(projectDir string, project *Project) (string, error) {
+	if _, err := os.Stat(filepath.Join(projectDir, spinManifestName)); err != nil {
+		return "", fmt.Errorf("%s not found in '%s', run 'spin azure link' in a Spin app directory", spinManifestName, projectDir)
+	}
+
+	data, err := json.MarshalIndent(project, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize project file: %w", err)
+	}
+
+	projectPath := filepath.Join(projectDir, ProjectFileName)
+	if err := writeFileAtomic(projectPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write project file: %w", err)
+	}
+
+	return projectPath, nil
+}
+
+// RecordBinding adds the binding to the project file of the linked app containing the working directory,
+// replacing an earlier binding of the same resource and identity. It returns the path of the updated
+// project file, or an empty path when the working directory is not inside a linked app.
+func RecordBinding(binding Binding) (string, error) {
+	project, projectPath, err := LoadProject()
+	if err != nil || project == nil {
+		return "", err
+	}
+
+	replaced := false
+	for i, b := range project.Bindings {
+		if b.Service == binding.Service && b.Name == binding.Name && b.ResourceGroup == binding.ResourceGroup && b.Identity == binding.Identity {
+			project.Bindings[i] = binding
+			replaced = true
+		}
+	}
+	if !replaced {
+		project.Bindings = append(project.Bindings, binding)
+	}
+
+	return SaveProject(filepath.Dir(projectPath), project)
+}
+
+// apply overrides the configuration with the values set in the project and returns the keys it set
+func (p *Project) apply(config *Config) []string {
+	var applied []string
+	for _, key := range Keys {
+		if field, ok := projectKeys[key.Name]; ok && *field(p) != "" {
+			*key.field(config) = *field(p)
+			applied = append(applied, key.Name)
+		}
+	}
+
+	return applied
+}
+
+// restore undoes apply for the values of config that still equal the project's, so saving a
+// configuration loaded inside a project keeps the global values it overrides
+func (p *Project) restore(config, global *Config) {
+	for _, key := range Keys {
+		if field, ok := projectKeys[key.Name]; ok && *field(p) != "" && *key.field(config) == *field(p) {
+			*key.field(config) = *key.field(global)
+		}
+	}
+}
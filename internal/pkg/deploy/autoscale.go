@@ -0,0 +1,234 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/kube"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// Autoscale configures horizontal scaling of a SpinApp
+type Autoscale struct {
+	MinReplicas int
+	MaxReplicas int
+	// CPUTarget is the average CPU utilization percentage to scale on, 0 disables CPU scaling
+	CPUTarget int
+	// ServiceBusQueue scales the SpinApp on the length of a Service Bus queue using KEDA
+	ServiceBusQueue string
+	// ServiceBusNamespace is the Service Bus namespace containing ServiceBusQueue
+	ServiceBusNamespace string
+}
+
+// usesKEDA reports whether the autoscaling needs KEDA rather than a plain HPA
+func (a *Autoscale) usesKEDA() bool {
+	return a.ServiceBusQueue != ""
+}
+
+// autoscalingPatch hands scaling over to the autoscaler
+const autoscalingPatch = `{"spec":{"enableAutoscaling":true,"replicas":null}}`
+
+// autoscalerKinds are the kinds of the resources configureAutoscaling creates, which are named after the SpinApp
+var autoscalerKinds = []string{"horizontalpodautoscaler", "scaledobject", "triggerauthentication"}
+
+// configureAutoscaling enables autoscaling on the SpinApp and creates an HPA or KEDA ScaledObject for it,
+// deleting the other kind of autoscaler left by an earlier deployment so they don't both scale the SpinApp
+func (s *Service) configureAutoscaling(spinAppName, namespace, identityClientID string, autoscale *Autoscale) error {
+	stale := "scaledobject"
+	if autoscale.usesKEDA() {
+		stale = "horizontalpodautoscaler"
+	}
+	if _, err := deleteAutoscaler(stale, spinAppName, namespace); err != nil {
+		return err
+	}
+
+	if autoscale.CPUTarget > 0 {
+		if err := warnMissingCPURequest(spinAppName, namespace); err != nil {
+			return err
+		}
+	}
+
+	// The Spin Operator rejects replicas together with enableAutoscaling, so the merge patch removes it
+	fmt.Printf("Enabling autoscaling for SpinApp '%s'...\n", spinAppName)
+	cmd := exec.Command(
+		"kubectl", "patch", "spinapp", spinAppName,
+		"-n", namespace,
+		"--type", "merge",
+		"-p", autoscalingPatch,
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to enable autoscaling for SpinApp '%s': %w", spinAppName, clierror.New(err, output))
+	}
+
+	if autoscale.usesKEDA() {
+		fmt.Printf("Creating KEDA ScaledObject for SpinApp '%s'...\n", spinAppName)
+		manifest := triggerAuthenticationManifest(spinAppName, namespace, identityClientID) +
+			"---" + scaledObjectManifest(spinAppName, namespace, autoscale)
+		if err := kube.ApplyManifest(manifest); err != nil {
+			return fmt.Errorf("failed to create KEDA ScaledObject for SpinApp '%s': %w", spinAppName, err)
+		}
+		return nil
+	}
+
+	fmt.Printf("Creating HorizontalPodAutoscaler for SpinApp '%s'...\n", spinAppName)
+	if err := kube.ApplyManifest(hpaManifest(spinAppName, namespace, autoscale)); err != nil {
+		return fmt.Errorf("failed to create HorizontalPodAutoscaler for SpinApp '%s': %w", spinAppName, err)
+	}
+
+	return nil
+}
+
+// removeAutoscaling deletes the autoscaler created by an earlier deployment with --autoscale and hands the
+// replicas back to the SpinApp. It runs before the SpinApp is applied, since the Spin Operator rejects the
+// replicas of the SpinApp while enableAutoscaling is set.
+func (s *Service) removeAutoscaling(spinAppName, namespace string) error {
+	removed := false
+	for _, kind := range autoscalerKinds {
+		name := spinAppName
+		if kind == "triggerauthentication" {
+			name = spinAppName + "-workload-identity"
+		}
+
+		deleted, err := deleteAutoscaler(kind, name, namespace)
+		if err != nil {
+			return err
+		}
+		removed = removed || deleted
+	}
+
+	if !removed {
+		return nil
+	}
+
+	fmt.Printf("Disabling autoscaling for SpinApp '%s'...\n", spinAppName)
+	cmd := exec.Command(
+		"kubectl", "patch", "spinapp", spinAppName,
+		"-n", namespace,
+		"--type", "merge",
+		"-p", `{"spec":{"enableAutoscaling":false}}`,
+	)
+
+	fmt.Println("Executing command:", strings.Join(cmd.Args, " "))
+
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to disable autoscaling for SpinApp '%s': %w", spinAppName, clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// deleteAutoscaler deletes a resource created for autoscaling and reports whether it existed. Clusters
+// without KEDA have no ScaledObject or TriggerAuthentication resource type, so there is nothing to delete.
+func deleteAutoscaler(kind, name, namespace string) (bool, error) {
+	cmd := exec.Command("kubectl", "delete", kind, name, "-n", namespace, "--ignore-not-found")
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		if strings.Contains(string(stderr), "doesn't have a resource type") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to delete %s '%s': %w", kind, name, clierror.New(err, stderr))
+	}
+
+	if deleted := strings.TrimSpace(string(output)); deleted != "" {
+		fmt.Println(deleted)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// warnMissingCPURequest warns when the SpinApp has no CPU request, without which Kubernetes cannot compute
+// the CPU utilization the autoscaler scales on
+func warnMissingCPURequest(spinAppName, namespace string) error {
+	cmd := exec.Command("kubectl", "get", "spinapp", spinAppName, "-n", namespace, "-o", "jsonpath={.spec.resources.requests.cpu}")
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get resources of SpinApp '%s': %w", spinAppName, clierror.New(err, stderr))
+	}
+
+	if strings.TrimSpace(string(output)) == "" {
+		fmt.Fprintf(os.Stderr, "Warning: SpinApp '%s' has no CPU request, so it will not be scaled on CPU utilization. "+
+			"Set spec.resources.requests.cpu in the SpinApp, e.g. 100m\n", spinAppName)
+	}
+
+	return nil
+}
+
+func hpaManifest(spinAppName, namespace string, autoscale *Autoscale) string {
+	return fmt.Sprintf(`
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: %s
+  minReplicas: %d
+  maxReplicas: %d
+  metrics:
+  - type: Resource
+    resource:
+      name: cpu
+      target:
+        type: Utilization
+        averageUtilization: %d
+`, spinAppName, namespace, spinAppName, autoscale.MinReplicas, autoscale.MaxReplicas, autoscale.CPUTarget)
+}
+
+func triggerAuthenticationManifest(spinAppName, namespace, identityClientID string) string {
+	return fmt.Sprintf(`
+apiVersion: keda.sh/v1alpha1
+kind: TriggerAuthentication
+metadata:
+  name: %s-workload-identity
+  namespace: %s
+spec:
+  podIdentity:
+    provider: azure-workload
+    identityId: %s
+`, spinAppName, namespace, identityClientID)
+}
+
+func scaledObjectManifest(spinAppName, namespace string, autoscale *Autoscale) string {
+	triggers := fmt.Sprintf(`
+  - type: azure-servicebus
+    metadata:
+      namespace: %s
+      queueName: %s
+      messageCount: "5"
+    authenticationRef:
+      name: %s-workload-identity`, autoscale.ServiceBusNamespace, autoscale.ServiceBusQueue, spinAppName)
+
+	if autoscale.CPUTarget > 0 {
+		triggers += fmt.Sprintf(`
+  - type: cpu
+    metricType: Utilization
+    metadata:
+      value: "%d"`, autoscale.CPUTarget)
+	}
+
+	return fmt.Sprintf(`
+apiVersion: keda.sh/v1alpha1
+kind: ScaledObject
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  scaleTargetRef:
+    name: %s
+  minReplicaCount: %d
+  maxReplicaCount: %d
+  triggers:%s
+`, spinAppName, namespace, spinAppName, autoscale.MinReplicas, autoscale.MaxReplicas, triggers)
+}
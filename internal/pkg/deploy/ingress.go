@@ -0,0 +1,173 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/aks"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/kube"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+const (
+	ingressAddressTimeout  = 5 * time.Minute
+	ingressAddressInterval = 5 * time.Second
+
+	certificateReadyTimeout  = 5 * time.Minute
+	certificateReadyInterval = 10 * time.Second
+)
+
+// hostLabelPattern matches a label of an RFC 1123 DNS name
+var hostLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidateHost checks that the host is a lowercase RFC 1123 DNS name, as required for the host of an Ingress rule.
+// The first label can be a wildcard.
+func ValidateHost(host string) error {
+	if len(host) > 253 {
+		return fmt.Errorf("invalid host '%s', DNS names are at most 253 characters long", host)
+	}
+
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if i == 0 && label == "*" && len(labels) > 1 {
+			continue
+		}
+		if !hostLabelPattern.MatchString(label) {
+			return fmt.Errorf("invalid host '%s', expected a DNS name such as app.example.com made of lowercase letters, digits, '-' and '.'", host)
+		}
+	}
+
+	return nil
+}
+
+// exposeSpinApp creates an Ingress for the SpinApp's service and waits for its external address.
+// When clusterIssuer is set, cert-manager is asked to issue a certificate for host.
+func (s *Service) exposeSpinApp(ctx context.Context, spinAppName, namespace, host, clusterIssuer string) error {
+	fmt.Printf("Creating Ingress for SpinApp '%s'...\n", spinAppName)
+	if err := kube.ApplyManifest(ingressManifest(spinAppName, namespace, host, clusterIssuer)); err != nil {
+		return fmt.Errorf("failed to create Ingress for SpinApp '%s': %w", spinAppName, err)
+	}
+
+	fmt.Println("Waiting for the load balancer address to be assigned...")
+	address, err := waitForIngressAddress(ctx, spinAppName, namespace)
+	if err != nil {
+		return err
+	}
+
+	scheme := "http"
+	if clusterIssuer != "" {
+		scheme = "https"
+	}
+
+	if host != "" {
+		fmt.Printf("SpinApp '%s' is exposed at %s://%s\n", spinAppName, scheme, host)
+		fmt.Printf("Point the DNS record for '%s' to %s\n", host, address)
+	} else {
+		fmt.Printf("SpinApp '%s' is exposed at %s://%s\n", spinAppName, scheme, address)
+	}
+
+	if clusterIssuer != "" {
+		waitForCertificate(ctx, tlsSecretName(spinAppName), namespace)
+	}
+
+	return nil
+}
+
+func tlsSecretName(spinAppName string) string {
+	return spinAppName + "-tls"
+}
+
+func ingressManifest(spinAppName, namespace, host, clusterIssuer string) string {
+	rule := "- http:"
+	if host != "" {
+		rule = fmt.Sprintf("- host: %s\n    http:", host)
+	}
+
+	annotations := ""
+	tls := ""
+	if clusterIssuer != "" {
+		annotations = fmt.Sprintf("\n  annotations:\n    cert-manager.io/cluster-issuer: %s", clusterIssuer)
+		tls = fmt.Sprintf("\n  tls:\n  - hosts:\n    - %s\n    secretName: %s", host, tlsSecretName(spinAppName))
+	}
+
+	return fmt.Sprintf(`
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: %s
+  namespace: %s%s
+spec:
+  ingressClassName: %s%s
+  rules:
+  %s
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: %s
+            port:
+              number: 80
+`, spinAppName, namespace, annotations, aks.AppRoutingIngressClass, tls, rule, spinAppName)
+}
+
+func waitForIngressAddress(ctx context.Context, name, namespace string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ingressAddressTimeout)
+	defer cancel()
+
+	for {
+		cmd := exec.Command(
+			"kubectl", "get", "ingress", name,
+			"-n", namespace,
+			"-o", "jsonpath={.status.loadBalancer.ingress[0].ip}{.status.loadBalancer.ingress[0].hostname}",
+		)
+		output, stderr, err := retry.Output(cmd)
+		if err != nil {
+			return "", fmt.Errorf("failed to get Ingress '%s': %w", name, clierror.New(err, stderr))
+		}
+
+		if address := strings.TrimSpace(string(output)); address != "" {
+			return address, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for a load balancer address for Ingress '%s', check it with 'kubectl get ingress %s -n %s'", name, name, namespace)
+		case <-time.After(ingressAddressInterval):
+		}
+	}
+}
+
+// waitForCertificate reports whether cert-manager issued the certificate in time.
+// Issuance depends on DNS pointing at the load balancer, so a timeout is not an error.
+func waitForCertificate(ctx context.Context, name, namespace string) {
+	ctx, cancel := context.WithTimeout(ctx, certificateReadyTimeout)
+	defer cancel()
+
+	fmt.Printf("Waiting for certificate '%s' to be issued...\n", name)
+	for {
+		cmd := exec.Command(
+			"kubectl", "get", "certificate", name,
+			"-n", namespace,
+			"--ignore-not-found",
+			"-o", `jsonpath={.status.conditions[?(@.type=="Ready")].status}`,
+		)
+		output, _, err := retry.Output(cmd)
+		if err == nil && strings.TrimSpace(string(output)) == "True" {
+			fmt.Printf("Certificate '%s' is ready\n", name)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Printf("Certificate '%s' is not ready yet, make sure DNS points to the load balancer and check it with 'kubectl describe certificate %s -n %s'\n", name, name, namespace)
+			return
+		case <-time.After(certificateReadyInterval):
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/aks"
+)
+
+func TestIngressManifest(t *testing.T) {
+	manifest := ingressManifest("my-app", "default", "", "")
+
+	if !strings.Contains(manifest, "ingressClassName: "+aks.AppRoutingIngressClass) {
+		t.Error("Expected Ingress to use the application routing ingress class")
+	}
+
+	if strings.Contains(manifest, "host:") {
+		t.Error("Expected Ingress without host to match any host")
+	}
+
+	if strings.Contains(manifest, "tls:") || strings.Contains(manifest, "cert-manager.io") {
+		t.Error("Expected Ingress without cluster issuer to have no TLS configuration")
+	}
+}
+
+func TestIngressManifestWithTLS(t *testing.T) {
+	manifest := ingressManifest("my-app", "default", "app.example.com", "letsencrypt")
+
+	expected := []string{
+		"cert-manager.io/cluster-issuer: letsencrypt",
+		"- host: app.example.com",
+		"secretName: my-app-tls",
+		"    - app.example.com",
+	}
+	for _, e := range expected {
+		if !strings.Contains(manifest, e) {
+			t.Errorf("Expected Ingress to contain '%s', got:\n%s", e, manifest)
+		}
+	}
+}
+
+func TestValidateHost(t *testing.T) {
+	valid := []string{"app.example.com", "localhost", "*.example.com", "my-app.eu-west.example.com"}
+	for _, host := range valid {
+		if err := ValidateHost(host); err != nil {
+			t.Errorf("Expected host '%s' to be valid, got %v", host, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"App.Example.com",
+		"app.example.com:8080",
+		"app example.com",
+		"app.example.com\n    http:",
+		"-app.example.com",
+		"app..example.com",
+		"app.*.example.com",
+		"*",
+		strings.Repeat("a", 64) + ".example.com",
+	}
+	for _, host := range invalid {
+		if err := ValidateHost(host); err == nil {
+			t.Errorf("Expected host '%s' to be rejected", host)
+		}
+	}
+}
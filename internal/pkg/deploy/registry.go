@@ -0,0 +1,89 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/aks"
+)
+
+const acrDomainSuffix = ".azurecr.io"
+
+type spinAppImage struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Image            string        `json:"image"`
+		ImagePullSecrets []interface{} `json:"imagePullSecrets"`
+	} `json:"spec"`
+	Items []spinAppImage `json:"items"`
+}
+
+// checkImagePullAccess warns when a SpinApp image comes from an Azure Container Registry that
+// the cluster's kubelet identity cannot pull from. It never fails the deployment.
+func (s *Service) checkImagePullAccess(ctx context.Context, spinAppYAMLPath string) {
+	cmd := exec.Command("kubectl", "apply", "--dry-run=client", "-f", spinAppYAMLPath, "-o", "json")
+	output, err := cmd.Output()
+	if err != nil {
+		return
+	}
+
+	var parsed spinAppImage
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return
+	}
+
+	objects := parsed.Items
+	if len(objects) == 0 {
+		objects = []spinAppImage{parsed}
+	}
+
+	aksService, err := aks.NewService(s.credential, s.subscriptionID)
+	if err != nil {
+		return
+	}
+
+	for _, object := range objects {
+		if object.Kind != "SpinApp" || len(object.Spec.ImagePullSecrets) > 0 {
+			continue
+		}
+
+		registry := acrName(object.Spec.Image)
+		if registry == "" {
+			continue
+		}
+
+		canPull, err := aksService.CanPullFromACR(ctx, registry)
+		if err != nil {
+			fmt.Printf("Warning: could not verify that the cluster can pull '%s': %v\n", object.Spec.Image, err)
+			continue
+		}
+
+		if !canPull {
+			fmt.Printf("Warning: the cluster cannot pull '%s' from container registry '%s', grant access using 'spin azure assign-role acr --registry %s'\n",
+				object.Spec.Image, registry, registry)
+		}
+	}
+}
+
+// registryHost returns the registry host of an image reference, defaulting to Docker Hub
+func registryHost(image string) string {
+	host, _, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return "docker.io"
+	}
+
+	return host
+}
+
+// acrName returns the name of the Azure Container Registry an image is pulled from, if any
+func acrName(image string) string {
+	host := registryHost(image)
+	if !strings.HasSuffix(host, acrDomainSuffix) {
+		return ""
+	}
+
+	return strings.TrimSuffix(host, acrDomainSuffix)
+}
@@ -0,0 +1,31 @@
+package deploy
+
+import "testing"
+
+func TestRegistryHost(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                               "docker.io",
+		"library/nginx:latest":                "docker.io",
+		"ghcr.io/spinkube/spin-hello:v1":      "ghcr.io",
+		"myregistry.azurecr.io/hello:v1":      "myregistry.azurecr.io",
+		"localhost:5000/hello":                "localhost:5000",
+		"localhost/hello":                     "localhost",
+		"myregistry.azurecr.io/team/app@sha2": "myregistry.azurecr.io",
+	}
+
+	for image, expected := range tests {
+		if host := registryHost(image); host != expected {
+			t.Errorf("Expected registry of '%s' to be '%s', got '%s'", image, expected, host)
+		}
+	}
+}
+
+func TestACRName(t *testing.T) {
+	if name := acrName("myregistry.azurecr.io/hello:v1"); name != "myregistry" {
+		t.Errorf("Expected ACR name 'myregistry', got '%s'", name)
+	}
+
+	if name := acrName("ghcr.io/spinkube/spin-hello:v1"); name != "" {
+		t.Errorf("Expected no ACR name for a GitHub image, got '%s'", name)
+	}
+}
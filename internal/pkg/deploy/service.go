@@ -8,7 +8,10 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
 	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/kube"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
 )
 
 type Service struct {
@@ -16,6 +19,25 @@ type Service struct {
 	subscriptionID string
 }
 
+// Options controls optional steps performed after the SpinApp is applied
+type Options struct {
+	// Expose creates an Ingress for the SpinApp using the AKS application routing add-on
+	Expose bool
+	// Host is the host name the Ingress routes to the SpinApp, if any
+	Host string
+	// ClusterIssuer is the cert-manager ClusterIssuer used to obtain a TLS certificate for Host, if any
+	ClusterIssuer string
+	// Autoscale configures an HPA or KEDA ScaledObject for the SpinApp, if set
+	Autoscale *Autoscale
+	// Variables are set on the SpinApp, Key Vault references are resolved through the Secrets Store CSI driver
+	Variables []Variable
+	// RuntimeConfigSecret is the Secret the SpinApp loads its Spin runtime config from, if any
+	RuntimeConfigSecret string
+	// IdentityClientID and TenantID identify the managed identity used for Key Vault references and KEDA scalers
+	IdentityClientID string
+	TenantID         string
+}
+
 func NewService(credential azcore.TokenCredential, subscriptionID string) *Service {
 	return &Service{
 		credential:     credential,
@@ -23,7 +45,7 @@ func NewService(credential azcore.TokenCredential, subscriptionID string) *Servi
 	}
 }
 
-func (s *Service) Deploy(ctx context.Context, spinAppYAMLPath, identityName string) error {
+func (s *Service) Deploy(ctx context.Context, spinAppYAMLPath, identityName string, opts Options) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
@@ -38,52 +60,89 @@ func (s *Service) Deploy(ctx context.Context, spinAppYAMLPath, identityName stri
 	}
 
 	// Get Kubernetes credentials for the current cluster
-	if err := s.getKubernetesCredentials(cfg.ClusterName, cfg.ResourceGroup); err != nil {
+	if err := kube.GetCredentials(s.subscriptionID, cfg.ClusterName, cfg.ResourceGroup); err != nil {
 		return err
 	}
 
 	// Verify service account exists
-	namespace := "default"
+	namespace := cfg.GetNamespace()
 	checkCmd := exec.Command("kubectl", "get", "serviceaccount", identityName, "-n", namespace, "--ignore-not-found")
-	output, err := checkCmd.CombinedOutput()
+	output, err := retry.CombinedOutput(checkCmd)
 	if err != nil {
-		return fmt.Errorf("failed to check if service account exists: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to check if service account exists: %w", clierror.New(err, output))
 	}
 
 	if !strings.Contains(string(output), identityName) {
 		return fmt.Errorf("service account '%s' not found in namespace '%s', please create it using 'spin azure cluster use --service-account=%s' or 'spin azure cluster create --service-account=%s'", identityName, namespace, identityName, identityName)
 	}
 
-	if err := s.deploySpinAppYAML(spinAppYAMLPath); err != nil {
+	s.checkImagePullAccess(ctx, spinAppYAMLPath)
+
+	spinAppName, err := s.getSpinAppName(spinAppYAMLPath, namespace)
+	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Successfully deployed Spin application from '%s' with identity '%s'\n", spinAppYAMLPath, identityName)
-	return nil
-}
+	if opts.Autoscale == nil && spinAppName != "" {
+		if err := s.removeAutoscaling(spinAppName, namespace); err != nil {
+			return err
+		}
+	}
+
+	if err := s.deploySpinAppYAML(spinAppYAMLPath, namespace, spinAppName); err != nil {
+		return err
+	}
 
-func (s *Service) getKubernetesCredentials(clusterName, resourceGroup string) error {
-	cmd := exec.Command(
-		"az", "aks", "get-credentials",
-		"--name", clusterName,
-		"--resource-group", resourceGroup,
-		"--subscription", s.subscriptionID,
-		"--overwrite-existing",
-	)
+	if len(opts.Variables) > 0 {
+		if spinAppName == "" {
+			return fmt.Errorf("no SpinApp found in %s, cannot set variables", spinAppYAMLPath)
+		}
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to get Kubernetes credentials: %w\nOutput: %s", err, string(output))
+		if err := s.configureVariables(spinAppName, namespace, opts.IdentityClientID, opts.TenantID, opts.Variables); err != nil {
+			return err
+		}
+	}
+
+	if opts.RuntimeConfigSecret != "" {
+		if spinAppName == "" {
+			return fmt.Errorf("no SpinApp found in %s, cannot set the runtime config", spinAppYAMLPath)
+		}
+
+		if err := s.setRuntimeConfigSecret(spinAppName, namespace, opts.RuntimeConfigSecret); err != nil {
+			return err
+		}
+	}
+
+	if opts.Autoscale != nil {
+		if spinAppName == "" {
+			return fmt.Errorf("no SpinApp found in %s, cannot configure autoscaling", spinAppYAMLPath)
+		}
+
+		if err := s.configureAutoscaling(spinAppName, namespace, opts.IdentityClientID, opts.Autoscale); err != nil {
+			return err
+		}
 	}
 
+	if opts.Expose {
+		if spinAppName == "" {
+			return fmt.Errorf("no SpinApp found in %s, cannot expose it", spinAppYAMLPath)
+		}
+
+		if err := s.exposeSpinApp(ctx, spinAppName, namespace, opts.Host, opts.ClusterIssuer); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Successfully deployed Spin application from '%s' with identity '%s'\n", spinAppYAMLPath, identityName)
 	return nil
 }
 
-func (s *Service) deploySpinAppYAML(spinAppYAMLPath string) error {
-	checkCmd := exec.Command("kubectl", "apply", "--dry-run=client", "-f", spinAppYAMLPath, "-o", "name")
-	output, err := checkCmd.CombinedOutput()
+// getSpinAppName returns the name of the SpinApp in the YAML file, empty when it has none
+func (s *Service) getSpinAppName(spinAppYAMLPath, namespace string) (string, error) {
+	checkCmd := exec.Command("kubectl", "apply", "--dry-run=client", "-f", spinAppYAMLPath, "-n", namespace, "-o", "name")
+	output, stderr, err := retry.Output(checkCmd)
 	if err != nil {
-		return fmt.Errorf("failed to parse YAML file: %w\nOutput: %s", err, string(output))
+		return "", fmt.Errorf("failed to parse YAML file: %w", clierror.New(err, stderr))
 	}
 
 	resourceNames := strings.Split(string(output), "\n")
@@ -98,16 +157,20 @@ func (s *Service) deploySpinAppYAML(spinAppYAMLPath string) error {
 		}
 	}
 
+	return spinAppName, nil
+}
+
+func (s *Service) deploySpinAppYAML(spinAppYAMLPath, namespace, spinAppName string) error {
 	if spinAppName != "" {
 		fmt.Printf("Deploying SpinApp '%s'\n", spinAppName)
 	} else {
 		fmt.Println("Deploying SpinApp resources")
 	}
 
-	cmd := exec.Command("kubectl", "apply", "-f", spinAppYAMLPath)
-	output, err = cmd.CombinedOutput()
+	cmd := exec.Command("kubectl", "apply", "-f", spinAppYAMLPath, "-n", namespace)
+	output, err := retry.CombinedOutput(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to apply SpinApp: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to apply SpinApp: %w", clierror.New(err, output))
 	}
 
 	if spinAppName != "" {
@@ -118,3 +181,26 @@ func (s *Service) deploySpinAppYAML(spinAppYAMLPath string) error {
 
 	return nil
 }
+
+// setRuntimeConfigSecret makes the SpinApp load its runtime config from the Secret
+func (s *Service) setRuntimeConfigSecret(spinAppName, namespace, secretName string) error {
+	checkCmd := exec.Command("kubectl", "get", "secret", secretName, "-n", namespace, "--ignore-not-found", "-o", "name")
+	output, stderr, err := retry.Output(checkCmd)
+	if err != nil {
+		return fmt.Errorf("failed to check if Secret '%s' exists: %w", secretName, clierror.New(err, stderr))
+	}
+
+	if strings.TrimSpace(string(output)) == "" {
+		return fmt.Errorf("Secret '%s' not found in namespace '%s', create it using 'spin azure assign-role' with --runtime-config-secret", secretName, namespace)
+	}
+
+	fmt.Printf("Loading runtime config of SpinApp '%s' from Secret '%s'...\n", spinAppName, secretName)
+	patch := fmt.Sprintf(`{"spec":{"runtimeConfig":{"loadFromSecret":%q}}}`, secretName)
+	cmd := exec.Command("kubectl", "patch", "spinapp", spinAppName, "-n", namespace, "--type", "merge", "-p", patch)
+	output, err = retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to set runtime config on SpinApp '%s': %w", spinAppName, clierror.New(err, output))
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/kube"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+const (
+	keyVaultReferencePrefix = "@keyvault:"
+	secretsStoreCSIDriver   = "secrets-store.csi.k8s.io"
+)
+
+var variableNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Variable is a Spin application variable set at deploy time, either to a plain value
+// or to a secret stored in Azure Key Vault
+type Variable struct {
+	Name  string
+	Value string
+	// KeyVault and Secret identify the Key Vault secret the variable is read from
+	KeyVault string
+	Secret   string
+}
+
+// ParseVariable parses a variable in the form key=value or key=@keyvault:<vault>/<secret>
+func ParseVariable(value string) (Variable, error) {
+	name, val, found := strings.Cut(value, "=")
+	if !found {
+		return Variable{}, fmt.Errorf("invalid variable '%s', expected the form key=value", value)
+	}
+
+	if !variableNamePattern.MatchString(name) {
+		return Variable{}, fmt.Errorf("invalid variable name '%s', Spin variable names must start with a lowercase letter and contain only lowercase letters, digits and underscores", name)
+	}
+
+	if !strings.HasPrefix(val, keyVaultReferencePrefix) {
+		return Variable{Name: name, Value: val}, nil
+	}
+
+	vault, secret, found := strings.Cut(strings.TrimPrefix(val, keyVaultReferencePrefix), "/")
+	if !found || vault == "" || secret == "" {
+		return Variable{}, fmt.Errorf("invalid Key Vault reference '%s', expected the form @keyvault:<vault>/<secret>", val)
+	}
+
+	return Variable{Name: name, KeyVault: vault, Secret: secret}, nil
+}
+
+// IsKeyVaultReference reports whether the variable is read from Azure Key Vault
+func (v Variable) IsKeyVaultReference() bool {
+	return v.KeyVault != ""
+}
+
+// configureVariables creates a SecretProviderClass per Key Vault and sets the variables on the SpinApp.
+// Key Vault secrets are synced into Kubernetes Secrets by the Secrets Store CSI driver, so their
+// values never pass through the CLI.
+func (s *Service) configureVariables(spinAppName, namespace, identityClientID, tenantID string, variables []Variable) error {
+	secretsByVault := make(map[string][]string)
+	for _, v := range variables {
+		if v.IsKeyVaultReference() && !slices.Contains(secretsByVault[v.KeyVault], v.Secret) {
+			secretsByVault[v.KeyVault] = append(secretsByVault[v.KeyVault], v.Secret)
+		}
+	}
+
+	vaults := make([]string, 0, len(secretsByVault))
+	for vault := range secretsByVault {
+		vaults = append(vaults, vault)
+	}
+	sort.Strings(vaults)
+
+	for _, vault := range vaults {
+		fmt.Printf("Creating SecretProviderClass for Key Vault '%s'...\n", vault)
+		manifest := secretProviderClassManifest(spinAppName, namespace, vault, identityClientID, tenantID, secretsByVault[vault])
+		if err := kube.ApplyManifest(manifest); err != nil {
+			return fmt.Errorf("failed to create SecretProviderClass for Key Vault '%s': %w", vault, err)
+		}
+	}
+
+	cmd := exec.Command("kubectl", "get", "spinapp", spinAppName, "-n", namespace, "-o", "json")
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get SpinApp '%s': %w", spinAppName, clierror.New(err, stderr))
+	}
+
+	var current struct {
+		Spec struct {
+			Variables    []map[string]interface{} `json:"variables"`
+			Volumes      []map[string]interface{} `json:"volumes"`
+			VolumeMounts []map[string]interface{} `json:"volumeMounts"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(output, &current); err != nil {
+		return fmt.Errorf("failed to parse SpinApp '%s': %w", spinAppName, err)
+	}
+
+	spec := map[string]interface{}{}
+
+	newVariables := make([]map[string]interface{}, 0, len(variables))
+	for _, v := range variables {
+		if v.IsKeyVaultReference() {
+			newVariables = append(newVariables, map[string]interface{}{
+				"name": v.Name,
+				"valueFrom": map[string]interface{}{
+					"secretKeyRef": map[string]interface{}{
+						"name": keyVaultSecretName(spinAppName, v.KeyVault),
+						"key":  v.Secret,
+					},
+				},
+			})
+		} else {
+			newVariables = append(newVariables, map[string]interface{}{
+				"name":  v.Name,
+				"value": v.Value,
+			})
+		}
+	}
+	spec["variables"] = mergeByName(current.Spec.Variables, newVariables)
+
+	if len(vaults) > 0 {
+		newVolumes := make([]map[string]interface{}, 0, len(vaults))
+		newVolumeMounts := make([]map[string]interface{}, 0, len(vaults))
+		for _, vault := range vaults {
+			volumeName := keyVaultVolumeName(vault)
+			newVolumes = append(newVolumes, map[string]interface{}{
+				"name": volumeName,
+				"csi": map[string]interface{}{
+					"driver":   secretsStoreCSIDriver,
+					"readOnly": true,
+					"volumeAttributes": map[string]interface{}{
+						"secretProviderClass": secretProviderClassName(spinAppName, vault),
+					},
+				},
+			})
+			newVolumeMounts = append(newVolumeMounts, map[string]interface{}{
+				"name":      volumeName,
+				"mountPath": "/mnt/secrets-store/" + vault,
+				"readOnly":  true,
+			})
+		}
+		spec["volumes"] = mergeByName(current.Spec.Volumes, newVolumes)
+		spec["volumeMounts"] = mergeByName(current.Spec.VolumeMounts, newVolumeMounts)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		return fmt.Errorf("failed to serialize SpinApp patch: %w", err)
+	}
+
+	fmt.Printf("Setting %d variable(s) on SpinApp '%s'...\n", len(variables), spinAppName)
+	cmd = exec.Command("kubectl", "patch", "spinapp", spinAppName, "-n", namespace, "--type", "merge", "-p", string(patch))
+	output, err = retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to set variables on SpinApp '%s': %w", spinAppName, clierror.New(err, output))
+	}
+
+	return nil
+}
+
+func secretProviderClassName(spinAppName, vault string) string {
+	return fmt.Sprintf("%s-%s", spinAppName, strings.ToLower(vault))
+}
+
+func keyVaultSecretName(spinAppName, vault string) string {
+	return secretProviderClassName(spinAppName, vault) + "-secrets"
+}
+
+func keyVaultVolumeName(vault string) string {
+	return "keyvault-" + strings.ToLower(vault)
+}
+
+func secretProviderClassManifest(spinAppName, namespace, vault, identityClientID, tenantID string, secrets []string) string {
+	var objects, data strings.Builder
+	for _, secret := range secrets {
+		fmt.Fprintf(&objects, "        - |\n          objectName: %s\n          objectType: secret\n", secret)
+		fmt.Fprintf(&data, "    - objectName: %s\n      key: %s\n", secret, secret)
+	}
+
+	return fmt.Sprintf(`
+apiVersion: secrets-store.csi.x-k8s.io/v1
+kind: SecretProviderClass
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  provider: azure
+  parameters:
+    usePodIdentity: "false"
+    clientID: %s
+    keyvaultName: %s
+    tenantId: %s
+    objects: |
+      array:
+%s  secretObjects:
+  - secretName: %s
+    type: Opaque
+    data:
+%s`, secretProviderClassName(spinAppName, vault), namespace, identityClientID, vault, tenantID,
+		objects.String(), keyVaultSecretName(spinAppName, vault), data.String())
+}
+
+// mergeByName replaces entries of current with entries of updates that have the same name,
+// and appends the remaining updates
+func mergeByName(current, updates []map[string]interface{}) []map[string]interface{} {
+	merged := make([]map[string]interface{}, 0, len(current)+len(updates))
+	replaced := make(map[interface{}]bool)
+	for _, entry := range current {
+		for _, update := range updates {
+			if update["name"] == entry["name"] {
+				entry = update
+				replaced[update["name"]] = true
+				break
+			}
+		}
+		merged = append(merged, entry)
+	}
+
+	for _, update := range updates {
+		if !replaced[update["name"]] {
+			merged = append(merged, update)
+		}
+	}
+
+	return merged
+}
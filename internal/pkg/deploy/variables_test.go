@@ -0,0 +1,74 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVariable(t *testing.T) {
+	v, err := ParseVariable("greeting=hello=world")
+	if err != nil {
+		t.Fatalf("Failed to parse variable: %v", err)
+	}
+
+	if v.Name != "greeting" || v.Value != "hello=world" || v.IsKeyVaultReference() {
+		t.Errorf("Expected plain variable greeting=hello=world, got %+v", v)
+	}
+
+	v, err = ParseVariable("api_key=@keyvault:my-vault/api-key")
+	if err != nil {
+		t.Fatalf("Failed to parse Key Vault reference: %v", err)
+	}
+
+	if !v.IsKeyVaultReference() || v.KeyVault != "my-vault" || v.Secret != "api-key" {
+		t.Errorf("Expected Key Vault reference to my-vault/api-key, got %+v", v)
+	}
+
+	for _, value := range []string{"greeting", "Greeting=hello", "1st=hello", "key=@keyvault:my-vault", "key=@keyvault:/secret"} {
+		if _, err := ParseVariable(value); err == nil {
+			t.Errorf("Expected '%s' to be rejected", value)
+		}
+	}
+}
+
+func TestMergeByName(t *testing.T) {
+	current := []map[string]interface{}{
+		{"name": "a", "value": "1"},
+		{"name": "b", "value": "2"},
+	}
+	updates := []map[string]interface{}{
+		{"name": "b", "value": "3"},
+		{"name": "c", "value": "4"},
+	}
+
+	merged := mergeByName(current, updates)
+	if len(merged) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(merged))
+	}
+
+	expected := []string{"a=1", "b=3", "c=4"}
+	for i, entry := range merged {
+		if got := entry["name"].(string) + "=" + entry["value"].(string); got != expected[i] {
+			t.Errorf("Expected entry %d to be '%s', got '%s'", i, expected[i], got)
+		}
+	}
+}
+
+func TestSecretProviderClassManifest(t *testing.T) {
+	manifest := secretProviderClassManifest("my-app", "default", "My-Vault", "client-id", "tenant-id", []string{"api-key", "db-password"})
+
+	expected := []string{
+		"name: my-app-my-vault\n",
+		"clientID: client-id",
+		"keyvaultName: My-Vault",
+		"tenantId: tenant-id",
+		"          objectName: db-password",
+		"  - secretName: my-app-my-vault-secrets",
+		"    - objectName: api-key\n      key: api-key",
+	}
+	for _, e := range expected {
+		if !strings.Contains(manifest, e) {
+			t.Errorf("Expected SecretProviderClass to contain '%s', got:\n%s", e, manifest)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package kube
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/config"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// GetCredentials merges the credentials of the AKS cluster into the kubeconfig and makes it the current context.
+// The command is not printed, so that commands with JSON output keep stdout clean.
+func GetCredentials(subscriptionID, clusterName, resourceGroup string) error {
+	cmd := exec.Command(
+		"az", "aks", "get-credentials",
+		"--name", clusterName,
+		"--resource-group", resourceGroup,
+		"--subscription", subscriptionID,
+		"--overwrite-existing",
+	)
+
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get Kubernetes credentials: %w", clierror.New(err, output))
+	}
+
+	return nil
+}
+
+// UseCurrentCluster makes kubectl use the cluster selected in the configuration
+func UseCurrentCluster(subscriptionID string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.ClusterName == "" || cfg.ResourceGroup == "" {
+		return fmt.Errorf("no cluster is currently selected, use 'spin azure cluster use' or 'spin azure cluster create' first")
+	}
+
+	return GetCredentials(subscriptionID, cfg.ClusterName, cfg.ResourceGroup)
+}
+
+// ApplyManifest writes the YAML or JSON manifest to a temporary file and applies it with kubectl
+func ApplyManifest(manifest string) error {
+	tempFile, err := os.CreateTemp("", "manifest-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write([]byte(manifest)); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	tempFile.Close()
+
+	cmd := exec.Command("kubectl", "apply", "-f", tempFile.Name())
+	output, err := retry.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to apply manifest: %w", clierror.New(err, output))
+	}
+
+	return nil
+}
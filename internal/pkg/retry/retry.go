@@ -0,0 +1,136 @@
+package retry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+)
+
+// DefaultMaxRetries is the number of retries of a failed external command unless --max-retries is set
+const (
+	DefaultMaxRetries = 3
+
+	defaultInitialBackoff = 2 * time.Second
+)
+
+var (
+	// MaxRetries is the number of times a command failing with a retryable error is run again
+	MaxRetries = DefaultMaxRetries
+
+	initialBackoff = defaultInitialBackoff
+	maxBackoff     = 30 * time.Second
+)
+
+// CombinedOutput runs the command like exec.Cmd.CombinedOutput, running it again with exponential
+// backoff while it fails with a retryable error such as throttling or an API server that is still starting.
+// Only use it for commands that are safe to run twice, run other commands with exec.Cmd.CombinedOutput.
+// A command reading from Stdin is run once, since its input cannot be read again.
+func CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Stdin != nil {
+		return cmd.CombinedOutput()
+	}
+
+	var output []byte
+	err := Do(strings.Join(cmd.Args, " "), func() error {
+		var err error
+		output, err = copyCommand(cmd).CombinedOutput()
+		if err != nil {
+			return clierror.New(err, output)
+		}
+		return nil
+	})
+
+	var cliErr *clierror.Error
+	if errors.As(err, &cliErr) {
+		return output, cliErr.Err
+	}
+
+	return output, err
+}
+
+// Output runs the command like CombinedOutput but returns its standard output and standard error
+// separately, so warnings written to standard error don't end up in values parsed from the output.
+// Standard error is what to pass to clierror.New when the command fails.
+func Output(cmd *exec.Cmd) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	run := func(attempt *exec.Cmd) error {
+		stdout.Reset()
+		stderr.Reset()
+		attempt.Stdout = &stdout
+		attempt.Stderr = &stderr
+		return attempt.Run()
+	}
+
+	if cmd.Stdin != nil {
+		err := run(cmd)
+		return stdout.Bytes(), stderr.Bytes(), err
+	}
+
+	err := Do(strings.Join(cmd.Args, " "), func() error {
+		if err := run(copyCommand(cmd)); err != nil {
+			return clierror.New(err, stderr.Bytes())
+		}
+		return nil
+	})
+
+	var cliErr *clierror.Error
+	if errors.As(err, &cliErr) {
+		return stdout.Bytes(), stderr.Bytes(), cliErr.Err
+	}
+
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// copyCommand returns a new command running the same program, since an exec.Cmd can only be run once
+func copyCommand(cmd *exec.Cmd) *exec.Cmd {
+	attempt := exec.Command(cmd.Args[0], cmd.Args[1:]...)
+	attempt.Env = cmd.Env
+	attempt.Dir = cmd.Dir
+
+	return attempt
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable or MaxRetries retries are used up
+func Do(description string, fn func() error) error {
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= MaxRetries || !retryable(err) {
+			return err
+		}
+
+		// Written to standard error so retries don't end up in JSON output
+		fmt.Fprintf(os.Stderr, "Retrying '%s' in %s (retry %d of %d): %s\n", description, backoff, attempt+1, MaxRetries, reason(err))
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func retryable(err error) bool {
+	var cliErr *clierror.Error
+	return errors.As(err, &cliErr) && cliErr.Retryable()
+}
+
+// reason returns the first line of the error output that explains the failure
+func reason(err error) string {
+	var cliErr *clierror.Error
+	if errors.As(err, &cliErr) {
+		for _, line := range strings.Split(cliErr.Output, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				return line
+			}
+		}
+	}
+
+	return err.Error()
+}
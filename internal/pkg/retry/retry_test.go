@@ -0,0 +1,68 @@
+package retry
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+)
+
+func TestDo(t *testing.T) {
+	initialBackoff = 0
+	defer func() { initialBackoff = defaultInitialBackoff }()
+
+	throttled := clierror.New(errors.New("exit status 1"), []byte("(TooManyRequests) Too many requests"))
+
+	calls := 0
+	err := Do("az aks create", func() error {
+		calls++
+		if calls < 3 {
+			return throttled
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected retries to succeed, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+
+	calls = 0
+	err = Do("az aks create", func() error {
+		calls++
+		return throttled
+	})
+	if !errors.Is(err, clierror.ErrThrottled) {
+		t.Errorf("Expected the last error to be returned, got %v", err)
+	}
+	if calls != MaxRetries+1 {
+		t.Errorf("Expected %d calls, got %d", MaxRetries+1, calls)
+	}
+
+	calls = 0
+	notFound := clierror.New(errors.New("exit status 3"), []byte("(ResourceGroupNotFound) Resource group 'my-rg' could not be found."))
+	err = Do("az aks show", func() error {
+		calls++
+		return notFound
+	})
+	if !errors.Is(err, clierror.ErrResourceNotFound) || calls != 1 {
+		t.Errorf("Expected a non-retryable error to be returned after 1 call, got %v after %d calls", err, calls)
+	}
+}
+
+func TestOutput(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo WARNING: deprecated >&2; echo value")
+
+	stdout, stderr, err := Output(cmd)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(stdout) != "value\n" {
+		t.Errorf("Expected stdout 'value', got %q", stdout)
+	}
+	if string(stderr) != "WARNING: deprecated\n" {
+		t.Errorf("Expected the warning on stderr, got %q", stderr)
+	}
+}
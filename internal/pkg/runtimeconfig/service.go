@@ -0,0 +1,151 @@
+package runtimeconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/clierror"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/kube"
+	"github.com/spinframework/spin-plugin-azure/internal/pkg/retry"
+)
+
+// SecretKey is the key of the Secret holding the runtime config, as expected by the Spin Operator
+// for SpinApps using runtimeConfig.loadFromSecret
+const SecretKey = "runtime-config.toml"
+
+// Section is a table of a Spin runtime config file, such as [key_value_store.default]
+type Section struct {
+	Table string
+	// Keys holds the keys of the table in the order they are written
+	Keys   []string
+	Values map[string]string
+}
+
+// Service stores Spin runtime config in Kubernetes Secrets of the current AKS cluster
+type Service struct {
+	credential     azcore.TokenCredential
+	subscriptionID string
+}
+
+// NewService creates a new runtime config service
+func NewService(credential azcore.TokenCredential, subscriptionID string) *Service {
+	return &Service{
+		credential:     credential,
+		subscriptionID: subscriptionID,
+	}
+}
+
+// CosmosKeyValueStore returns a key-value store backed by an Azure Cosmos DB container. No key is set,
+// so Spin authenticates with the workload identity of the SpinApp.
+func CosmosKeyValueStore(label, account, database, container string) Section {
+	return Section{
+		Table: "key_value_store." + label,
+		Keys:  []string{"type", "account", "database", "container"},
+		Values: map[string]string{
+			"type":      "azure_cosmos",
+			"account":   account,
+			"database":  database,
+			"container": container,
+		},
+	}
+}
+
+// RedisKeyValueStore returns a key-value store backed by Redis
+func RedisKeyValueStore(label, url string) Section {
+	return Section{
+		Table: "key_value_store." + label,
+		Keys:  []string{"type", "url"},
+		Values: map[string]string{
+			"type": "redis",
+			"url":  url,
+		},
+	}
+}
+
+// String renders the section as TOML
+func (s Section) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]\n", s.Table)
+	for _, key := range s.Keys {
+		fmt.Fprintf(&b, "%s = %q\n", key, s.Values[key])
+	}
+
+	return b.String()
+}
+
+// AddSection adds the section to the runtime config stored in the Secret, replacing a section with the
+// same table, and creates the Secret if it does not exist yet
+func (s *Service) AddSection(ctx context.Context, secretName, namespace string, section Section) error {
+	if err := kube.UseCurrentCluster(s.subscriptionID); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(
+		"kubectl", "get", "secret", secretName,
+		"-n", namespace,
+		"--ignore-not-found",
+		"-o", `jsonpath={.data.runtime-config\.toml}`,
+	)
+	output, stderr, err := retry.Output(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get Secret '%s': %w", secretName, clierror.New(err, stderr))
+	}
+
+	existing, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(output)))
+	if err != nil {
+		return fmt.Errorf("failed to decode runtime config of Secret '%s': %w", secretName, err)
+	}
+
+	manifest, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       "Opaque",
+		"metadata": map[string]interface{}{
+			"name":      secretName,
+			"namespace": namespace,
+		},
+		"stringData": map[string]string{
+			SecretKey: Merge(string(existing), section),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize Secret '%s': %w", secretName, err)
+	}
+
+	fmt.Printf("Writing [%s] to the runtime config in Secret '%s'...\n", section.Table, secretName)
+	if err := kube.ApplyManifest(string(manifest)); err != nil {
+		return fmt.Errorf("failed to write runtime config to Secret '%s': %w", secretName, err)
+	}
+
+	return nil
+}
+
+// Merge replaces the table of the section in the runtime config, or appends the section when the
+// runtime config does not have that table
+func Merge(runtimeConfig string, section Section) string {
+	header := fmt.Sprintf("[%s]", section.Table)
+
+	var kept []string
+	skipping := false
+	for _, line := range strings.Split(runtimeConfig, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			skipping = trimmed == header
+		}
+		if !skipping {
+			kept = append(kept, line)
+		}
+	}
+
+	merged := strings.TrimSpace(strings.Join(kept, "\n"))
+	if merged == "" {
+		return section.String()
+	}
+
+	return merged + "\n\n" + section.String()
+}
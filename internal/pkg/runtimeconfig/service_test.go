@@ -0,0 +1,39 @@
+package runtimeconfig
+
+import "testing"
+
+func TestSectionString(t *testing.T) {
+	section := CosmosKeyValueStore("default", "my-cosmos", "spin", "kv")
+	expected := `[key_value_store.default]
+type = "azure_cosmos"
+account = "my-cosmos"
+database = "spin"
+container = "kv"
+`
+	if section.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, section.String())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	cosmos := CosmosKeyValueStore("default", "my-cosmos", "spin", "kv")
+	redis := RedisKeyValueStore("cache", "rediss://my-redis.redis.cache.windows.net:6380")
+
+	merged := Merge("", cosmos)
+	if merged != cosmos.String() {
+		t.Errorf("Expected merging into an empty runtime config to return the section, got:\n%s", merged)
+	}
+
+	merged = Merge(merged, redis)
+	expected := cosmos.String() + "\n" + redis.String()
+	if merged != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, merged)
+	}
+
+	updated := CosmosKeyValueStore("default", "other-cosmos", "spin", "kv")
+	merged = Merge(merged, updated)
+	expected = redis.String() + "\n" + updated.String()
+	if merged != expected {
+		t.Errorf("Expected the existing table to be replaced, got:\n%s", merged)
+	}
+}